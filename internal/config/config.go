@@ -6,8 +6,23 @@ import (
 )
 
 type Config struct {
-	DBUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBUrl                string `json:"db_url"`
+	CurrentUserName      string `json:"current_user_name"`
+	FetchTimeout         string `json:"fetch_timeout,omitempty"`
+	MaxFeedSize          int64  `json:"max_feed_size,omitempty"`
+	SummaryLength        int    `json:"summary_length,omitempty"`
+	TerminalWidth        int    `json:"terminal_width,omitempty"`
+	DisableAfterFailures int    `json:"disable_after_failures,omitempty"`
+	UserAgent            string `json:"user_agent,omitempty"`
+	ProxyURL             string `json:"proxy_url,omitempty"`
+	InsecureSkipVerify   bool   `json:"insecure_skip_verify,omitempty"`
+	WebsubCallbackURL    string `json:"websub_callback_url,omitempty"`
+	WebsubListen         string `json:"websub_listen,omitempty"`
+	HostDelay            string `json:"host_delay,omitempty"`
+	HostConcurrency      int    `json:"host_concurrency,omitempty"`
+	CheckRobots          bool   `json:"check_robots,omitempty"`
+	AggWorkers           int    `json:"agg_workers,omitempty"`
+	AggBatchSize         int    `json:"agg_batch_size,omitempty"`
 }
 
 func Read() (Config, error) {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
@@ -22,10 +23,10 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, categories
 ) 
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.categories,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -42,13 +43,14 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.NullUUID
-	FeedID    uuid.NullUUID
-	FeedName  string
-	UserName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.NullUUID
+	FeedID     uuid.NullUUID
+	Categories []string
+	FeedName   string
+	UserName   string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -66,6 +68,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		pq.Array(&i.Categories),
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -88,8 +91,10 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.categories,
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
     users.name AS user_name
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
@@ -98,13 +103,16 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.NullUUID
-	FeedID    uuid.NullUUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.NullUUID
+	FeedID      uuid.NullUUID
+	Categories  []string
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl string
+	UserName    string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUID) ([]GetFeedFollowsForUserRow, error) {
@@ -122,7 +130,10 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUI
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			pq.Array(&i.Categories),
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -137,3 +148,40 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.NullUUI
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1, updated_at = NOW()
+WHERE feed_follows.feed_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM feed_follows AS existing
+        WHERE existing.feed_id = $1 AND existing.user_id = feed_follows.user_id
+    )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.NullUUID
+	FromFeedID uuid.NullUUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const setFeedFollowCategories = `-- name: SetFeedFollowCategories :exec
+UPDATE feed_follows
+SET categories = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowCategoriesParams struct {
+	UserID     uuid.NullUUID
+	FeedID     uuid.NullUUID
+	Categories []string
+}
+
+func (q *Queries) SetFeedFollowCategories(ctx context.Context, arg SetFeedFollowCategoriesParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowCategories, arg.UserID, arg.FeedID, pq.Array(arg.Categories))
+	return err
+}
@@ -10,8 +10,97 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = NOW(), updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE status <> 'disabled'
+        AND (last_fetched_at IS NULL OR (
+            last_fetched_at + MAKE_INTERVAL(mins => refresh_interval_minutes) <= NOW()
+            AND last_fetched_at < $1::TIMESTAMPTZ
+        ))
+        AND NOT (EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC')::INTEGER = ANY(skip_hours))
+        AND NOT (EXTRACT(DOW FROM NOW() AT TIME ZONE 'UTC')::INTEGER = ANY(skip_days))
+        AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+        AND NOT EXISTS (
+            SELECT 1 FROM websub_subscriptions
+            WHERE websub_subscriptions.feed_id = feeds.id
+                AND websub_subscriptions.status = 'active'
+                AND websub_subscriptions.lease_expires_at > $2::TIMESTAMP
+                AND feeds.last_fetched_at + MAKE_INTERVAL(mins => $3::INTEGER) > NOW()
+        )
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT $4
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids
+`
+
+type ClaimFeedsToFetchParams struct {
+	CycleStart          time.Time
+	LeaseValidAt        time.Time
+	PushFallbackMinutes int32
+	BatchSize           int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch,
+		arg.CycleStart,
+		arg.LeaseValidAt,
+		arg.PushFallbackMinutes,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Status,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.WebsubHub,
+			&i.WebsubTopic,
+			&i.FetchFullText,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.ImageUrl,
+			&i.Language,
+			&i.RefreshIntervalMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.NextAttemptAt,
+			&i.LegacyGuids,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -22,7 +111,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids
 `
 
 type CreateFeedParams struct {
@@ -52,12 +141,136 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Status,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.WebsubHub,
+		&i.WebsubTopic,
+		&i.FetchFullText,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.ImageUrl,
+		&i.Language,
+		&i.RefreshIntervalMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.NextAttemptAt,
+		&i.LegacyGuids,
 	)
 	return i, err
 }
 
+const deferFeed = `-- name: DeferFeed :exec
+UPDATE feeds
+SET next_attempt_at = NOW() + MAKE_INTERVAL(secs => $1::INTEGER), updated_at = NOW()
+WHERE id = $2
+`
+
+type DeferFeedParams struct {
+	RetryInSeconds int32
+	ID             uuid.UUID
+}
+
+func (q *Queries) DeferFeed(ctx context.Context, arg DeferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeed, arg.RetryInSeconds, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :execrows
+UPDATE feeds
+SET status = 'active', consecutive_failures = 0, last_error = '', next_attempt_at = NULL, updated_at = NOW()
+WHERE url = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFeed, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDatabaseTime = `-- name: GetDatabaseTime :one
+SELECT NOW()::TIMESTAMPTZ AS now
+`
+
+func (q *Queries) GetDatabaseTime(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getDatabaseTime)
+	var now time.Time
+	err := row.Scan(&now)
+	return now, err
+}
+
+const getDisabledFeeds = `-- name: GetDisabledFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids FROM feeds
+WHERE status = 'disabled'
+ORDER BY updated_at DESC
+`
+
+func (q *Queries) GetDisabledFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getDisabledFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Status,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.WebsubHub,
+			&i.WebsubTopic,
+			&i.FetchFullText,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.ImageUrl,
+			&i.Language,
+			&i.RefreshIntervalMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.NextAttemptAt,
+			&i.LegacyGuids,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids FROM feeds
 WHERE url = $1
 `
 
@@ -72,12 +285,68 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Status,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.WebsubHub,
+		&i.WebsubTopic,
+		&i.FetchFullText,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.ImageUrl,
+		&i.Language,
+		&i.RefreshIntervalMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.NextAttemptAt,
+		&i.LegacyGuids,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Status,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.WebsubHub,
+		&i.WebsubTopic,
+		&i.FetchFullText,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.ImageUrl,
+		&i.Language,
+		&i.RefreshIntervalMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.NextAttemptAt,
+		&i.LegacyGuids,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -97,6 +366,93 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Status,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.WebsubHub,
+			&i.WebsubTopic,
+			&i.FetchFullText,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.ImageUrl,
+			&i.Language,
+			&i.RefreshIntervalMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.NextAttemptAt,
+			&i.LegacyGuids,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsDueForWebsub = `-- name: GetFeedsDueForWebsub :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.status, feeds.consecutive_failures, feeds.last_error, feeds.websub_hub, feeds.websub_topic, feeds.fetch_full_text, feeds.title, feeds.site_url, feeds.description, feeds.image_url, feeds.language, feeds.refresh_interval_minutes, feeds.skip_hours, feeds.skip_days, feeds.next_attempt_at, feeds.legacy_guids FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.websub_hub <> ''
+    AND feeds.status <> 'disabled'
+    AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+    AND (
+        websub_subscriptions.feed_id IS NULL
+        OR websub_subscriptions.hub_url <> feeds.websub_hub
+        OR websub_subscriptions.topic_url <> feeds.websub_topic
+        OR (websub_subscriptions.status = 'active' AND websub_subscriptions.lease_expires_at < $1::TIMESTAMP)
+        OR (websub_subscriptions.status = 'pending' AND websub_subscriptions.requested_at < $2::TIMESTAMP)
+    )
+`
+
+type GetFeedsDueForWebsubParams struct {
+	RenewBefore time.Time
+	RetryBefore time.Time
+}
+
+func (q *Queries) GetFeedsDueForWebsub(ctx context.Context, arg GetFeedsDueForWebsubParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsDueForWebsub, arg.RenewBefore, arg.RetryBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.Status,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.WebsubHub,
+			&i.WebsubTopic,
+			&i.FetchFullText,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.ImageUrl,
+			&i.Language,
+			&i.RefreshIntervalMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.NextAttemptAt,
+			&i.LegacyGuids,
 		); err != nil {
 			return nil, err
 		}
@@ -111,14 +467,36 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at NULLS FIRST 
-LIMIT 1
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $1,
+    status = CASE
+        WHEN $2::BOOLEAN OR consecutive_failures + 1 >= $3::INTEGER THEN 'disabled'
+        ELSE 'erroring'
+    END,
+    next_attempt_at = NOW() + MAKE_INTERVAL(secs => $4::INTEGER),
+    updated_at = NOW()
+WHERE id = $5
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, status, consecutive_failures, last_error, websub_hub, websub_topic, fetch_full_text, title, site_url, description, image_url, language, refresh_interval_minutes, skip_hours, skip_days, next_attempt_at, legacy_guids
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type RecordFeedFailureParams struct {
+	LastError      string
+	Disable        bool
+	Threshold      int32
+	RetryInSeconds int32
+	ID             uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.Disable,
+		arg.Threshold,
+		arg.RetryInSeconds,
+		arg.ID,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -128,17 +506,170 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.Status,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.WebsubHub,
+		&i.WebsubTopic,
+		&i.FetchFullText,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.ImageUrl,
+		&i.Language,
+		&i.RefreshIntervalMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.NextAttemptAt,
+		&i.LegacyGuids,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET status = 'active', consecutive_failures = 0, last_error = '', next_attempt_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const setFeedFetchFullText = `-- name: SetFeedFetchFullText :execrows
+UPDATE feeds
+SET fetch_full_text = $2, updated_at = NOW()
+WHERE url = $1
+`
+
+type SetFeedFetchFullTextParams struct {
+	Url           string
+	FetchFullText bool
+}
+
+func (q *Queries) SetFeedFetchFullText(ctx context.Context, arg SetFeedFetchFullTextParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFetchFullText, arg.Url, arg.FetchFullText)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         string
+	LastModified string
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedLegacyGuids = `-- name: UpdateFeedLegacyGuids :exec
+UPDATE feeds
+SET legacy_guids = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedLegacyGuidsParams struct {
+	ID          uuid.UUID
+	LegacyGuids bool
+}
+
+func (q *Queries) UpdateFeedLegacyGuids(ctx context.Context, arg UpdateFeedLegacyGuidsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedLegacyGuids, arg.ID, arg.LegacyGuids)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, site_url = $3, description = $4, image_url = $5, language = $6, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       string
+	SiteUrl     string
+	Description string
+	ImageUrl    string
+	Language    string
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.SiteUrl,
+		arg.Description,
+		arg.ImageUrl,
+		arg.Language,
+	)
+	return err
+}
+
+const updateFeedRefreshHints = `-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET refresh_interval_minutes = $2, skip_hours = $3, skip_days = $4, updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type UpdateFeedRefreshHintsParams struct {
+	ID                     uuid.UUID
+	RefreshIntervalMinutes int32
+	SkipHours              []int32
+	SkipDays               []int32
+}
+
+func (q *Queries) UpdateFeedRefreshHints(ctx context.Context, arg UpdateFeedRefreshHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRefreshHints,
+		arg.ID,
+		arg.RefreshIntervalMinutes,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
+
+const updateFeedWebsub = `-- name: UpdateFeedWebsub :exec
+UPDATE feeds
+SET websub_hub = $2, websub_topic = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedWebsubParams struct {
+	ID          uuid.UUID
+	WebsubHub   string
+	WebsubTopic string
+}
+
+func (q *Queries) UpdateFeedWebsub(ctx context.Context, arg UpdateFeedWebsubParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedWebsub, arg.ID, arg.WebsubHub, arg.WebsubTopic)
 	return err
 }
@@ -11,22 +11,53 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	MimeType        string
+	Length          int64
+	DurationSeconds int32
+	ThumbnailUrl    string
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.NullUUID
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Name                   string
+	Url                    string
+	UserID                 uuid.NullUUID
+	LastFetchedAt          sql.NullTime
+	Etag                   string
+	LastModified           string
+	Status                 string
+	ConsecutiveFailures    int32
+	LastError              string
+	WebsubHub              string
+	WebsubTopic            string
+	FetchFullText          bool
+	Title                  string
+	SiteUrl                string
+	Description            string
+	ImageUrl               string
+	Language               string
+	RefreshIntervalMinutes int32
+	SkipHours              []int32
+	SkipDays               []int32
+	NextAttemptAt          sql.NullTime
+	LegacyGuids            bool
 }
 
 type FeedFollow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.NullUUID
-	FeedID    uuid.NullUUID
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.NullUUID
+	FeedID     uuid.NullUUID
+	Categories []string
 }
 
 type Post struct {
@@ -38,6 +69,11 @@ type Post struct {
 	Description string
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	Content     string
+	Categories  []string
+	Article     string
+	Authors     []string
 }
 
 type User struct {
@@ -46,3 +82,15 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 }
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	Status         string
+	RequestedAt    time.Time
+	LeaseExpiresAt sql.NullTime
+}
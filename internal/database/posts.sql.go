@@ -11,10 +11,34 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const adoptLegacyPostGuid = `-- name: AdoptLegacyPostGuid :exec
+UPDATE posts
+SET guid = $1, updated_at = NOW()
+WHERE posts.feed_id = $2
+    AND posts.guid = $3
+    AND posts.url = $3
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = $2 AND existing.guid = $1
+    )
+`
+
+type AdoptLegacyPostGuidParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) AdoptLegacyPostGuid(ctx context.Context, arg AdoptLegacyPostGuidParams) error {
+	_, err := q.db.ExecContext(ctx, adoptLegacyPostGuid, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, authors, categories)
 VALUES (
     $1,
     $2,
@@ -23,9 +47,14 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, categories, article, authors
 `
 
 type CreatePostParams struct {
@@ -37,6 +66,10 @@ type CreatePostParams struct {
 	Description string
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	Content     string
+	Authors     []string
+	Categories  []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -49,6 +82,10 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Guid,
+		arg.Content,
+		pq.Array(arg.Authors),
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -60,12 +97,17 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+		pq.Array(&i.Categories),
+		&i.Article,
+		pq.Array(&i.Authors),
 	)
 	return i, err
 }
 
 const getAllPosts = `-- name: GetAllPosts :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, categories, article, authors FROM posts
 `
 
 func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
@@ -86,6 +128,11 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.Content,
+			pq.Array(&i.Categories),
+			&i.Article,
+			pq.Array(&i.Authors),
 		); err != nil {
 			return nil, err
 		}
@@ -100,8 +147,42 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
 	return items, nil
 }
 
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, categories, article, authors FROM posts
+WHERE url = $1
+    AND feed_id IN (SELECT feed_id FROM feed_follows WHERE user_id = $2)
+ORDER BY published_at DESC NULLS LAST
+LIMIT 1
+`
+
+type GetPostForUserParams struct {
+	Url    string
+	UserID uuid.NullUUID
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.Url, arg.UserID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+		pq.Array(&i.Categories),
+		&i.Article,
+		pq.Array(&i.Authors),
+	)
+	return i, err
+}
+
 const getPostFromURL = `-- name: GetPostFromURL :one
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, categories, article, authors FROM posts
 WHERE url = $1
 `
 
@@ -117,31 +198,53 @@ func (q *Queries) GetPostFromURL(ctx context.Context, url string) (Post, error)
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+		pq.Array(&i.Categories),
+		&i.Article,
+		pq.Array(&i.Authors),
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT title, url, description, published_at FROM posts WHERE feed_id IN 
+SELECT id, title, url, description, published_at, authors, categories, article FROM posts WHERE feed_id IN 
     (SELECT feed_id FROM feed_follows WHERE user_id = $1)
+    AND ($2::TEXT = '' OR EXISTS (
+        SELECT 1 FROM unnest(posts.authors) AS author WHERE lower(author) = lower($2::TEXT)
+    ))
+    AND ($3::TEXT = '' OR EXISTS (
+        SELECT 1 FROM unnest(posts.categories) AS category WHERE lower(category) = lower($3::TEXT)
+    ))
 ORDER BY published_at DESC NULLS LAST
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.NullUUID
-	Limit  int32
+	UserID   uuid.NullUUID
+	Author   string
+	Category string
+	Limit    int32
 }
 
 type GetPostsForUserRow struct {
+	ID          uuid.UUID
 	Title       string
 	Url         string
 	Description string
 	PublishedAt sql.NullTime
+	Authors     []string
+	Categories  []string
+	Article     string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -150,10 +253,14 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.Title,
 			&i.Url,
 			&i.Description,
 			&i.PublishedAt,
+			pq.Array(&i.Authors),
+			pq.Array(&i.Categories),
+			&i.Article,
 		); err != nil {
 			return nil, err
 		}
@@ -169,7 +276,7 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 }
 
 const getPostsFromFeed = `-- name: GetPostsFromFeed :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, categories, article, authors FROM posts
 WHERE feed_id = $1
 `
 
@@ -191,6 +298,11 @@ func (q *Queries) GetPostsFromFeed(ctx context.Context, feedID uuid.UUID) ([]Pos
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.Content,
+			pq.Array(&i.Categories),
+			&i.Article,
+			pq.Array(&i.Authors),
 		); err != nil {
 			return nil, err
 		}
@@ -205,6 +317,26 @@ func (q *Queries) GetPostsFromFeed(ctx context.Context, feedID uuid.UUID) ([]Pos
 	return items, nil
 }
 
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1, updated_at = NOW()
+WHERE posts.feed_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = $1 AND existing.guid = posts.guid
+    )
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
 const resetPosts = `-- name: ResetPosts :exec
 DELETE FROM posts
 `
@@ -213,3 +345,19 @@ func (q *Queries) ResetPosts(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetPosts)
 	return err
 }
+
+const updatePostArticle = `-- name: UpdatePostArticle :exec
+UPDATE posts
+SET article = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePostArticleParams struct {
+	ID      uuid.UUID
+	Article string
+}
+
+func (q *Queries) UpdatePostArticle(ctx context.Context, arg UpdatePostArticleParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostArticle, arg.ID, arg.Article)
+	return err
+}
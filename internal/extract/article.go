@@ -0,0 +1,402 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNoContent is returned when a page has no recognisable article body.
+var ErrNoContent = errors.New("error: no article content found on page")
+
+// minArticleLength is the least amount of text, in bytes, worth keeping as
+// an article.
+const minArticleLength = 100
+
+// removedElements never hold article text.
+var removedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "object": true,
+	"embed": true, "form": true, "button": true, "input": true, "select": true,
+	"textarea": true, "svg": true, "template": true, "nav": true, "aside": true,
+	"footer": true, "link": true, "meta": true, "canvas": true, "dialog": true,
+}
+
+// blockElements stop a <div> from being scored as a paragraph of its own.
+var blockElements = map[string]bool{
+	"blockquote": true, "dl": true, "div": true, "ol": true, "p": true, "pre": true,
+	"table": true, "ul": true, "section": true, "article": true, "figure": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|menu|modal|newsletter|pager|pagination|popup|related|remark|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|widget`)
+	maybePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|story|text|blog`)
+	negativePattern = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|social|tags|tool|widget|ad-|ads`)
+	spacePattern    = regexp.MustCompile(`\s+`)
+)
+
+// Article finds the main content of an HTML page, readability style: text
+// blocks are scored by length and punctuation, their scores flow up to the
+// containing elements, and the best container is kept along with any
+// siblings that score nearly as well. Navigation, comments, widgets and
+// link-heavy blocks are dropped, and the result is returned as cleaned HTML
+// with links resolved against pageURL.
+func Article(body []byte, contentType, pageURL string) (string, error) {
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	prune(doc)
+	scores := scoreParagraphs(doc)
+	top := topCandidate(doc, scores)
+	if top == nil {
+		return "", ErrNoContent
+	}
+
+	article := gatherSiblings(top, scores)
+	clean(article, base)
+	if len(textContent(article)) < minArticleLength {
+		return "", ErrNoContent
+	}
+
+	var out strings.Builder
+	for child := article.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&out, child); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// prune removes elements that never belong to the article and those whose
+// class or id marks them as page furniture.
+func prune(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode || (child.Type == html.ElementNode && unwanted(child)) {
+			n.RemoveChild(child)
+		} else {
+			prune(child)
+		}
+		child = next
+	}
+}
+
+func unwanted(n *html.Node) bool {
+	if removedElements[n.Data] || hidden(n) {
+		return true
+	}
+	switch n.Data {
+	case "html", "body", "article", "main":
+		return false
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	if attr(n, "role") == "complementary" || attr(n, "role") == "navigation" {
+		return true
+	}
+	return unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match)
+}
+
+func hidden(n *html.Node) bool {
+	if _, ok := hasAttr(n, "hidden"); ok {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") ||
+		attr(n, "aria-hidden") == "true"
+}
+
+// scoreParagraphs gives every text block a score and adds it to its parent,
+// half of it to the grandparent and a third to the great-grandparent.
+func scoreParagraphs(doc *html.Node) map[*html.Node]float64 {
+	scores := map[*html.Node]float64{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && isParagraph(n) {
+			text := textContent(n)
+			if len(text) >= 25 {
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+				ancestor := n.Parent
+				for level := 0; level < 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+					if _, ok := scores[ancestor]; !ok {
+						scores[ancestor] = initialScore(ancestor)
+					}
+					switch level {
+					case 0:
+						scores[ancestor] += score
+					case 1:
+						scores[ancestor] += score / 2
+					default:
+						scores[ancestor] += score / float64(level*3)
+					}
+					ancestor = ancestor.Parent
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return scores
+}
+
+func isParagraph(n *html.Node) bool {
+	switch n.Data {
+	case "p", "pre", "td", "blockquote":
+		return true
+	case "div", "section":
+		// a div of bare text is a paragraph in all but name
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && blockElements[child.Data] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.Data {
+	case "article":
+		score += 10
+	case "div", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativePattern.MatchString(value) {
+			weight -= 25
+		}
+		if positivePattern.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// topCandidate picks the scored element with the best score once link
+// density is taken into account, falling back to <body>.
+func topCandidate(doc *html.Node, scores map[*html.Node]float64) *html.Node {
+	var top *html.Node
+	best := 0.0
+	// walk in document order so ties go to the earlier element
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if score, ok := scores[n]; ok {
+			score *= 1 - linkDensity(n)
+			scores[n] = score
+			if top == nil || score > best {
+				top, best = n, score
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	if top == nil {
+		return findElement(doc, "body")
+	}
+	return top
+}
+
+// gatherSiblings moves the top candidate, and those siblings that look like
+// part of the same article, into a new container.
+func gatherSiblings(top *html.Node, scores map[*html.Node]float64) *html.Node {
+	container := &html.Node{Type: html.ElementNode, Data: "div"}
+	if top.Parent == nil || top.Data == "body" {
+		moveChildren(top, container)
+		return container
+	}
+
+	threshold := math.Max(10, scores[top]*0.2)
+	var keep []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top || relatedSibling(sibling, scores, threshold) {
+			keep = append(keep, sibling)
+		}
+	}
+	for _, n := range keep {
+		n.Parent.RemoveChild(n)
+		container.AppendChild(n)
+	}
+	return container
+}
+
+func relatedSibling(n *html.Node, scores map[*html.Node]float64, threshold float64) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if score, ok := scores[n]; ok && score >= threshold {
+		return true
+	}
+	if n.Data != "p" {
+		return false
+	}
+	text := textContent(n)
+	density := linkDensity(n)
+	return (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.HasSuffix(text, "."))
+}
+
+// clean strips what is left of the page furniture inside the article, drops
+// all attributes but link and image targets, and resolves those against base.
+func clean(n *html.Node, base *url.URL) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.ElementNode {
+			if cluttered(child) {
+				n.RemoveChild(child)
+				child = next
+				continue
+			}
+			cleanAttributes(child, base)
+			clean(child, base)
+		}
+		child = next
+	}
+}
+
+// cluttered reports whether a container inside the article is mostly links
+// or marked as something other than content.
+func cluttered(n *html.Node) bool {
+	switch n.Data {
+	case "div", "section", "ul", "ol", "table", "header":
+	default:
+		return false
+	}
+	text := textContent(n)
+	if findElement(n, "pre") != nil || (findElement(n, "img") != nil && len(text) < 25) {
+		return false
+	}
+	if classWeight(n) < 0 {
+		return true
+	}
+	return linkDensity(n) > 0.5 && len(text) < 500
+}
+
+func cleanAttributes(n *html.Node, base *url.URL) {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		switch {
+		case n.Data == "a" && a.Key == "href",
+			n.Data == "img" && a.Key == "src":
+			if resolved := resolve(base, a.Val); resolved != "" {
+				kept = append(kept, html.Attribute{Key: a.Key, Val: resolved})
+			}
+		case n.Data == "img" && a.Key == "alt",
+			(n.Data == "td" || n.Data == "th") && (a.Key == "colspan" || a.Key == "rowspan"):
+			kept = append(kept, html.Attribute{Key: a.Key, Val: a.Val})
+		}
+	}
+	n.Attr = kept
+}
+
+// resolve makes href absolute, returning "" for anything but http(s) URLs so
+// javascript: and data: targets cannot survive.
+func resolve(base *url.URL, href string) string {
+	resolved, err := base.Parse(strings.TrimSpace(href))
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	return resolved.String()
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			linked += len(textContent(c))
+			return
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+func textContent(n *html.Node) string {
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			text.WriteString(c.Data)
+			text.WriteString(" ")
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(text.String(), " "))
+}
+
+func moveChildren(from, to *html.Node) {
+	for child := from.FirstChild; child != nil; child = from.FirstChild {
+		from.RemoveChild(child)
+		to.AppendChild(child)
+	}
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := hasAttr(n, key)
+	return value
+}
+
+func hasAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,220 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Document is an OPML 1.0 or 2.0 subscription list.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a subscription, when it has an xmlUrl, or a folder of
+// further outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Category string    `xml:"category,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+
+	// Other holds the attributes not matched above, so that exporters
+	// which get the case wrong (xmlurl, htmlURL) can still be read.
+	Other []xml.Attr `xml:",any,attr"`
+}
+
+// Subscription is a feed listed in a document, with the folders it was
+// filed under flattened into categories.
+type Subscription struct {
+	Title      string
+	XMLURL     string
+	HTMLURL    string
+	Categories []string
+}
+
+// Parse reads an OPML document, decoding any declared character set.
+func Parse(r io.Reader) (*Document, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	// exported files are often hand edited; accept stray entities and tags
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error: failed to parse OPML - %v", err)
+	}
+	return &doc, nil
+}
+
+// Subscriptions lists every outline with an xmlUrl in document order. The
+// titles of enclosing folders become a category path such as "Tech/Go",
+// and the OPML 2.0 category attribute adds its own comma separated paths.
+func (d *Document) Subscriptions() []Subscription {
+	var subscriptions []Subscription
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, outline := range outlines {
+			outline.normalize()
+			if outline.XMLURL == "" {
+				name := outline.name()
+				if name == "" {
+					walk(outline.Outlines, folders)
+				} else {
+					walk(outline.Outlines, append(folders[:len(folders):len(folders)], name))
+				}
+				continue
+			}
+
+			subscription := Subscription{
+				Title:   outline.name(),
+				XMLURL:  outline.XMLURL,
+				HTMLURL: outline.HTMLURL,
+			}
+			if len(folders) > 0 {
+				subscription.Categories = append(subscription.Categories, strings.Join(folders, "/"))
+			}
+			for _, category := range strings.Split(outline.Category, ",") {
+				category = strings.Trim(strings.TrimSpace(category), "/")
+				if category != "" && !contains(subscription.Categories, category) {
+					subscription.Categories = append(subscription.Categories, category)
+				}
+			}
+			subscriptions = append(subscriptions, subscription)
+
+			// some readers nest feeds under a feed; keep those too
+			walk(outline.Outlines, folders)
+		}
+	}
+	walk(d.Body.Outlines, nil)
+	return subscriptions
+}
+
+// New builds an OPML 2.0 document listing subscriptions. Each subscription
+// is filed in a folder named after its first category, nested on "/", and
+// all of its categories are also listed in the category attribute.
+func New(title string, subscriptions []Subscription) *Document {
+	doc := &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, subscription := range subscriptions {
+		outline := Outline{
+			Text:    subscription.Title,
+			Title:   subscription.Title,
+			Type:    "rss",
+			XMLURL:  subscription.XMLURL,
+			HTMLURL: subscription.HTMLURL,
+		}
+		var categories []string
+		for _, category := range subscription.Categories {
+			categories = append(categories, "/"+strings.Trim(category, "/"))
+		}
+		outline.Category = strings.Join(categories, ",")
+
+		outlines := &doc.Body.Outlines
+		if len(subscription.Categories) > 0 {
+			for _, folder := range strings.Split(strings.Trim(subscription.Categories[0], "/"), "/") {
+				outlines = folderOutlines(outlines, folder)
+			}
+		}
+		*outlines = append(*outlines, outline)
+	}
+	return doc
+}
+
+// folderOutlines returns the children of the folder named name among
+// outlines, adding the folder if it is not there yet.
+func folderOutlines(outlines *[]Outline, name string) *[]Outline {
+	for i := range *outlines {
+		folder := &(*outlines)[i]
+		if folder.XMLURL == "" && folder.Text == name {
+			return &folder.Outlines
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1].Outlines
+}
+
+// Write encodes the document, with an XML declaration, to w.
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("error: failed to write OPML - %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// normalize fills the known attributes from differently cased variants and
+// trims the URLs.
+func (o *Outline) normalize() {
+	for _, attr := range o.Other {
+		switch strings.ToLower(attr.Name.Local) {
+		case "xmlurl":
+			if o.XMLURL == "" {
+				o.XMLURL = attr.Value
+			}
+		case "htmlurl":
+			if o.HTMLURL == "" {
+				o.HTMLURL = attr.Value
+			}
+		case "title":
+			if o.Title == "" {
+				o.Title = attr.Value
+			}
+		case "text":
+			if o.Text == "" {
+				o.Text = attr.Value
+			}
+		}
+	}
+	o.XMLURL = strings.TrimSpace(o.XMLURL)
+	o.HTMLURL = strings.TrimSpace(o.HTMLURL)
+}
+
+func (o *Outline) name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package pubdate
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// layouts are tried in order against a normalized date string: weekdays
+// removed, month names reduced to English abbreviations and zone names
+// replaced by numeric offsets.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05 -0700",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"20060102T150405Z0700",
+	"20060102",
+
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006 3:04 PM -0700",
+	"2 Jan 2006 3:04 PM",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04:05",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04",
+	"2 Jan 2006",
+	"2 Jan 06",
+	"2-Jan-2006 15:04:05 -0700",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05",
+	"2-Jan-2006",
+
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 15:04 -0700",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006 3:04 PM -0700",
+	"Jan 2 2006 3:04 PM",
+	"Jan 2 2006 3:04:05 PM",
+	"Jan 2 2006",
+	"Jan 2006",
+}
+
+// yearlessLayouts lack a year, which is inferred from the reference time.
+var yearlessLayouts = []string{
+	time.Stamp,
+	"Jan 2 15:04",
+	"2 Jan 15:04:05",
+	"2 Jan 15:04",
+}
+
+// zoneOffsets resolves the zone names seen in feeds. Go's parser accepts
+// unknown abbreviations with a zero offset, so they are rewritten first.
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000", "WET": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800", "HST": "-1000",
+	"AST": "-0400", "ADT": "-0300", "NST": "-0330", "NDT": "-0230",
+	"BST": "+0100", "IST": "+0530", "WEST": "+0100",
+	"CET": "+0100", "CEST": "+0200", "MET": "+0100", "MEST": "+0200",
+	"EET": "+0200", "EEST": "+0300", "MSK": "+0300",
+	"HKT": "+0800", "SGT": "+0800", "AWST": "+0800", "CCT": "+0800",
+	"JST": "+0900", "KST": "+0900",
+	"ACST": "+0930", "ACDT": "+1030", "AEST": "+1000", "AEDT": "+1100",
+	"NZST": "+1200", "NZDT": "+1300",
+}
+
+// monthNames maps English and common European month names and abbreviations
+// to the abbreviations Go's layouts use.
+var monthNames = map[string]string{}
+
+func init() {
+	months := [][]string{
+		{"Jan", "january", "januar", "janvier", "janv", "enero", "ene", "gennaio", "gen", "januari", "janeiro", "stycznia", "styczeń", "sty"},
+		{"Feb", "february", "februar", "février", "fevrier", "févr", "fevr", "febrero", "febbraio", "februari", "fevereiro", "fev", "lutego", "luty", "lut"},
+		{"Mar", "march", "märz", "maerz", "mär", "mars", "marzo", "maart", "mrt", "março", "marco", "marca", "marzec"},
+		{"Apr", "april", "avril", "avr", "abril", "abr", "aprile", "kwietnia", "kwiecień", "kwi"},
+		{"May", "mai", "mayo", "maggio", "mag", "mei", "maio", "maja", "maj"},
+		{"Jun", "june", "juni", "juin", "junio", "giugno", "giu", "junho", "czerwca", "czerwiec", "cze"},
+		{"Jul", "july", "juli", "juillet", "juil", "julio", "luglio", "lug", "julho", "lipca", "lipiec", "lip"},
+		{"Aug", "august", "août", "aout", "agosto", "ago", "augustus", "sierpnia", "sierpień", "sie"},
+		{"Sep", "sept", "september", "septembre", "septiembre", "settembre", "set", "setembro", "września", "wrzesień", "wrz"},
+		{"Oct", "october", "oktober", "okt", "octobre", "octubre", "ottobre", "ott", "outubro", "out", "października", "październik", "paź"},
+		{"Nov", "november", "novembre", "noviembre", "novembro", "listopada", "listopad", "lis"},
+		{"Dec", "december", "dezember", "dez", "décembre", "decembre", "déc", "diciembre", "dic", "dicembre", "dezembro", "grudnia", "grudzień", "gru"},
+	}
+	for _, names := range months {
+		for _, name := range names {
+			monthNames[strings.ToLower(name)] = names[0]
+		}
+	}
+}
+
+// fillerWords join date parts in prose dates such as "12 de marzo de 2024"
+// or "Jan 2, 2006 at 3:04 PM".
+var fillerWords = map[string]bool{
+	"at": true, "of": true, "de": true, "del": true, "le": true, "à": true, "um": true, "den": true,
+}
+
+var (
+	leadingWordPattern = regexp.MustCompile(`^\s*[^\d\s,]+\.?\s*,`)
+	weekdayPattern     = regexp.MustCompile(`^(?i)(mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)[a-z]*\.?$`)
+	ordinalPattern     = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th|\.)$`)
+	commentPattern     = regexp.MustCompile(`\([^)]*\)`)
+	gmtOffsetPattern   = regexp.MustCompile(`^(?i)(?:GMT|UTC)([+-]\d{1,2})(?::?(\d{2}))?$`)
+)
+
+// Parse reads a feed publication date in any of the formats feeds use in
+// practice. ok is false when no format matched.
+func Parse(value string) (time.Time, bool) {
+	return ParseAt(value, time.Now())
+}
+
+// ParseAt is Parse with an explicit reference time, used to infer missing
+// years and to clamp dates in the future back to now. Times are returned in
+// UTC so that dates from different zones order correctly.
+func ParseAt(value string, now time.Time) (time.Time, bool) {
+	normalized := normalize(value)
+	if normalized == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return clamp(t, now).UTC(), true
+		}
+	}
+
+	for _, layout := range yearlessLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t.UTC(), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func clamp(t, now time.Time) time.Time {
+	if t.After(now) {
+		return now
+	}
+	return t
+}
+
+// normalize rewrites a date into the vocabulary the layouts expect.
+func normalize(value string) string {
+	// a word followed by a comma at the start is a weekday in any language
+	value = leadingWordPattern.ReplaceAllString(value, "")
+	value = commentPattern.ReplaceAllString(value, " ")
+	value = strings.ReplaceAll(value, ",", " ")
+	fields := strings.Fields(value)
+
+	var normalized []string
+	for i, field := range fields {
+		if (i == 0 && weekdayPattern.MatchString(field)) || fillerWords[strings.ToLower(field)] {
+			continue
+		}
+		if match := ordinalPattern.FindStringSubmatch(strings.ToLower(field)); match != nil {
+			field = match[1]
+		}
+		if month, ok := monthNames[strings.TrimSuffix(strings.ToLower(field), ".")]; ok {
+			field = month
+		}
+		if offset, ok := zoneOffsets[strings.ToUpper(field)]; ok && i > 0 {
+			field = offset
+		}
+		if match := gmtOffsetPattern.FindStringSubmatch(field); match != nil {
+			field = numericOffset(match[1], match[2])
+		}
+		if upper := strings.ToUpper(field); upper == "AM" || upper == "PM" {
+			field = upper
+		}
+		normalized = append(normalized, field)
+	}
+
+	// "2-Jan-2006" style dates may carry localized month names too
+	for i, field := range normalized {
+		parts := strings.Split(field, "-")
+		if len(parts) == 3 {
+			if month, ok := monthNames[strings.ToLower(parts[1])]; ok {
+				normalized[i] = parts[0] + "-" + month + "-" + parts[2]
+			}
+		}
+	}
+
+	return strings.Join(normalized, " ")
+}
+
+// numericOffset formats the hours and minutes of a "GMT+2" style zone as "+0200".
+func numericOffset(hours, minutes string) string {
+	sign := hours[:1]
+	hours = hours[1:]
+	if len(hours) == 1 {
+		hours = "0" + hours
+	}
+	if minutes == "" {
+		minutes = "00"
+	}
+	return sign + hours + minutes
+}
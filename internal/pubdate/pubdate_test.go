@@ -0,0 +1,100 @@
+package pubdate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		// RFC 822 and 1123 as RSS specifies, with and without weekday and seconds
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"02 Jan 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 2 Jan 2006 15:04 -0700", time.Date(2006, 1, 2, 22, 4, 0, 0, time.UTC)},
+		{"Mon, 02 Jan 06 15:04:05 +0100", time.Date(2006, 1, 2, 14, 4, 5, 0, time.UTC)},
+		{"Monday, 02 January 2006 15:04:05 UT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Tue, 3 Jun 2008 11:05:30 +0200", time.Date(2008, 6, 3, 9, 5, 30, 0, time.UTC)},
+
+		// ISO 8601 and RFC 3339 as Atom and JSON Feed use
+		{"2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05.999999+02:00", time.Date(2006, 1, 2, 13, 4, 5, 999999000, time.UTC)},
+		{"2006-01-02T15:04Z", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"2006-01-02T15:04:05-0500", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2006/01/02 15:04", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"20060102T150405Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"20060102", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+
+		// named zones, which Go would otherwise read as UTC
+		{"Mon, 02 Jan 2006 15:04:05 EST", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 PDT", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 CEST", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 JST", time.Date(2006, 1, 2, 6, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 IST", time.Date(2006, 1, 2, 9, 34, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT+2", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 UTC-05:30", time.Date(2006, 1, 2, 20, 34, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 +0000 (UTC)", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+
+		// prose dates, ordinals and 12-hour clocks
+		{"January 2, 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"Jan 2nd, 2006 at 3:04 PM", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"Sept. 21st 2010 9:30 am", time.Date(2010, 9, 21, 9, 30, 0, 0, time.UTC)},
+		{"2 January 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2-Jan-2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Jan 2 15:04:05 -0700 2006", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"March 2006", time.Date(2006, 3, 1, 0, 0, 0, 0, time.UTC)},
+
+		// localized month and weekday names
+		{"Mo, 02 Mär 2020 10:00:00 +0100", time.Date(2020, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{"2. Dezember 2019", time.Date(2019, 12, 2, 0, 0, 0, 0, time.UTC)},
+		{"lundi, 3 février 2020 08:15", time.Date(2020, 2, 3, 8, 15, 0, 0, time.UTC)},
+		{"12 de marzo de 2024", time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{"5 ottobre 2021 18:30", time.Date(2021, 10, 5, 18, 30, 0, 0, time.UTC)},
+		{"21 maart 2022", time.Date(2022, 3, 21, 0, 0, 0, 0, time.UTC)},
+		{"7 outubro 2023", time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC)},
+		{"14 października 2022", time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{"1-déc-2019", time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)},
+
+		// no year: the most recent such date not after now
+		{"Jun 10 08:00:00", time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC)},
+		{"Dec 24 18:00", time.Date(2023, 12, 24, 18, 0, 0, 0, time.UTC)},
+		{"3 Jan 09:15", time.Date(2024, 1, 3, 9, 15, 0, 0, time.UTC)},
+
+		// dates in the future are clamped to now
+		{"2030-01-01T00:00:00Z", now},
+		{"Sat, 15 Jun 2024 13:00:00 +0000", now},
+		{"Sat, 15 Jun 2024 13:00:00 +0200", time.Date(2024, 6, 15, 11, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAt(tt.value, now)
+		if !ok {
+			t.Errorf("ParseAt(%q) failed", tt.value)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseAt(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+		if got.Location() != time.UTC {
+			t.Errorf("ParseAt(%q) returned location %v, want UTC", tt.value, got.Location())
+		}
+	}
+}
+
+func TestParseAtRejects(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	for _, value := range []string{"", "   ", "yesterday", "not a date", "32 Foo 2020", "2006-13-45"} {
+		if got, ok := ParseAt(value, now); ok {
+			t.Errorf("ParseAt(%q) = %v, want failure", value, got)
+		}
+	}
+}
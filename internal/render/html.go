@@ -0,0 +1,413 @@
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Options controls how HTML is laid out for the terminal.
+type Options struct {
+	// Width is the column to wrap at; zero or less disables wrapping.
+	Width int
+	// MaxLength caps the body text in runes, cutting at a word boundary;
+	// zero or less disables truncation.
+	MaxLength int
+	// BaseURL resolves relative link targets when set.
+	BaseURL string
+}
+
+// skippedElements never produce visible text, or are unsafe to show.
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "iframe": true,
+	"object": true, "embed": true, "form": true, "button": true, "select": true,
+	"textarea": true, "svg": true, "template": true,
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true,
+	"footer": true, "aside": true, "main": true, "nav": true, "figure": true,
+	"figcaption": true, "table": true, "tr": true, "dl": true, "dt": true,
+	"dd": true, "hr": true, "address": true, "details": true, "summary": true,
+}
+
+type block struct {
+	first     string
+	rest      string
+	text      string
+	pre       bool
+	item      bool
+	underline rune
+}
+
+type list struct {
+	ordered bool
+	count   int
+}
+
+type renderer struct {
+	base   *url.URL
+	blocks []block
+	text   strings.Builder
+	links  []string
+
+	lists   []list
+	quotes  int
+	pre     int
+	heading rune
+	bullet  string
+}
+
+// HTMLToText converts an HTML fragment into wrapped plain text. Scripts,
+// styles, embeds and tracking pixels are dropped, links become numbered
+// footnotes, and lists, headings and quotes keep a readable shape.
+func HTMLToText(src string, options Options) string {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return sanitize(src)
+	}
+
+	r := &renderer{}
+	if options.BaseURL != "" {
+		r.base, _ = url.Parse(options.BaseURL)
+	}
+	r.walk(doc)
+	r.flush()
+
+	blocks := r.blocks
+	if options.MaxLength > 0 {
+		blocks = truncate(blocks, options.MaxLength)
+	}
+
+	var out strings.Builder
+	for i, b := range blocks {
+		if i > 0 {
+			out.WriteString("\n")
+			// consecutive preformatted lines and list items stay together
+			together := (b.pre && blocks[i-1].pre) || (b.item && blocks[i-1].item)
+			if !together {
+				out.WriteString("\n")
+			}
+		}
+		out.WriteString(b.layout(options.Width))
+	}
+
+	footnotes := referencedFootnotes(blocks, r.links)
+	if len(footnotes) > 0 {
+		out.WriteString("\n")
+		for _, n := range footnotes {
+			out.WriteString(fmt.Sprintf("\n[%d] %s", n, r.links[n-1]))
+		}
+	}
+	return out.String()
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.writeText(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			r.walk(child)
+		}
+		return
+	}
+
+	tag := n.Data
+	if skippedElements[tag] {
+		return
+	}
+
+	switch {
+	case tag == "br":
+		r.flush()
+		return
+	case tag == "img":
+		r.writeImage(n)
+		return
+	case tag == "a":
+		r.walkChildren(n)
+		r.writeLink(attr(n, "href"))
+		return
+	case tag == "ul" || tag == "ol":
+		r.flush()
+		r.lists = append(r.lists, list{ordered: tag == "ol"})
+		r.walkChildren(n)
+		r.flush()
+		r.lists = r.lists[:len(r.lists)-1]
+		return
+	case tag == "li":
+		r.flush()
+		r.bullet = "• "
+		if len(r.lists) > 0 {
+			current := &r.lists[len(r.lists)-1]
+			current.count++
+			if current.ordered {
+				r.bullet = strconv.Itoa(current.count) + ". "
+			}
+		}
+		r.walkChildren(n)
+		r.flush()
+		r.bullet = ""
+		return
+	case tag == "blockquote":
+		r.flush()
+		r.quotes++
+		r.walkChildren(n)
+		r.flush()
+		r.quotes--
+		return
+	case tag == "pre":
+		r.flush()
+		r.pre++
+		r.walkChildren(n)
+		r.flush()
+		r.pre--
+		return
+	case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6':
+		r.flush()
+		r.heading = '-'
+		if tag == "h1" {
+			r.heading = '='
+		}
+		r.walkChildren(n)
+		r.flush()
+		r.heading = 0
+		return
+	case blockElements[tag]:
+		r.flush()
+		r.walkChildren(n)
+		r.flush()
+		return
+	}
+
+	r.walkChildren(n)
+}
+
+func (r *renderer) walkChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+func (r *renderer) writeText(data string) {
+	data = sanitize(data)
+	if r.pre > 0 {
+		r.text.WriteString(data)
+		return
+	}
+
+	collapsed := strings.Join(strings.Fields(data), " ")
+	if collapsed == "" {
+		if data != "" && r.text.Len() > 0 {
+			r.space()
+		}
+		return
+	}
+	if unicode.IsSpace(rune(data[0])) {
+		r.space()
+	}
+	r.text.WriteString(collapsed)
+	last, _ := utf8.DecodeLastRuneInString(data)
+	if unicode.IsSpace(last) {
+		r.space()
+	}
+}
+
+func (r *renderer) space() {
+	current := r.text.String()
+	if current != "" && !strings.HasSuffix(current, " ") {
+		r.text.WriteString(" ")
+	}
+}
+
+func (r *renderer) writeLink(href string) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return
+	}
+	if r.base != nil {
+		if resolved, err := r.base.Parse(href); err == nil {
+			href = resolved.String()
+		}
+	}
+	if strings.TrimSpace(r.text.String()) == "" {
+		return
+	}
+	r.links = append(r.links, sanitize(href))
+	r.text.WriteString(fmt.Sprintf("[%d]", len(r.links)))
+}
+
+// writeImage keeps an image's alt text and drops tracking pixels entirely.
+func (r *renderer) writeImage(n *html.Node) {
+	if attr(n, "width") == "1" || attr(n, "height") == "1" || attr(n, "width") == "0" || attr(n, "height") == "0" {
+		return
+	}
+	alt := strings.Join(strings.Fields(sanitize(attr(n, "alt"))), " ")
+	if alt == "" {
+		return
+	}
+	r.space()
+	r.text.WriteString("[image: " + alt + "]")
+	r.space()
+}
+
+// flush closes the text gathered so far into a block carrying the current
+// list and quote prefixes.
+func (r *renderer) flush() {
+	text := r.text.String()
+	r.text.Reset()
+	if r.pre == 0 {
+		text = strings.TrimSpace(text)
+	} else {
+		text = strings.Trim(text, "\n")
+	}
+	if text == "" {
+		return
+	}
+
+	indent := strings.Repeat("> ", r.quotes)
+	if len(r.lists) > 0 {
+		indent += strings.Repeat("  ", len(r.lists)-1)
+	}
+	b := block{first: indent, rest: indent, text: text, pre: r.pre > 0, item: len(r.lists) > 0, underline: r.heading}
+	if r.bullet != "" {
+		b.first = indent + r.bullet
+		b.rest = indent + strings.Repeat(" ", utf8.RuneCountInString(r.bullet))
+		r.bullet = ""
+	}
+	r.blocks = append(r.blocks, b)
+}
+
+func (b block) layout(width int) string {
+	if b.pre {
+		lines := strings.Split(b.text, "\n")
+		for i := range lines {
+			lines[i] = b.rest + "    " + lines[i]
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	lines := wrap(b.text, width-utf8.RuneCountInString(b.rest))
+	for i := range lines {
+		if i == 0 {
+			lines[i] = b.first + lines[i]
+		} else {
+			lines[i] = b.rest + lines[i]
+		}
+	}
+	if b.underline != 0 {
+		longest := 0
+		for _, line := range lines {
+			longest = max(longest, utf8.RuneCountInString(line)-utf8.RuneCountInString(b.rest))
+		}
+		lines = append(lines, b.rest+strings.Repeat(string(b.underline), longest))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap breaks text into lines of at most width runes, letting single words
+// longer than the width overflow rather than splitting them.
+func wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if width <= 0 {
+		return []string{strings.Join(words, " ")}
+	}
+
+	var lines []string
+	var line strings.Builder
+	lineLength := 0
+	for _, word := range words {
+		wordLength := utf8.RuneCountInString(word)
+		if lineLength > 0 && lineLength+1+wordLength > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			line.WriteString(" ")
+			lineLength++
+		}
+		line.WriteString(word)
+		lineLength += wordLength
+	}
+	if lineLength > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// truncate keeps blocks until maxLength runes of text have been used,
+// cutting the last one at a word boundary and marking it with an ellipsis.
+func truncate(blocks []block, maxLength int) []block {
+	remaining := maxLength
+	for i, b := range blocks {
+		length := utf8.RuneCountInString(b.text)
+		if length <= remaining {
+			remaining -= length
+			continue
+		}
+
+		runes := []rune(b.text)
+		cut := string(runes[:remaining])
+		if space := strings.LastIndexAny(cut, " \n"); space > 0 {
+			cut = cut[:space]
+		}
+		cut = strings.TrimRight(cut, " \n")
+		if cut == "" {
+			return blocks[:i]
+		}
+		b.text = cut + "…"
+		b.underline = 0
+		return append(blocks[:i:i], b)
+	}
+	return blocks
+}
+
+func referencedFootnotes(blocks []block, links []string) []int {
+	var referenced []int
+	for n := 1; n <= len(links); n++ {
+		marker := fmt.Sprintf("[%d]", n)
+		for _, b := range blocks {
+			if strings.Contains(b.text, marker) {
+				referenced = append(referenced, n)
+				break
+			}
+		}
+	}
+	return referenced
+}
+
+var escapeSequencePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// sanitize strips escape sequences and control characters so feed content
+// cannot drive the terminal.
+func sanitize(s string) string {
+	s = escapeSequencePattern.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
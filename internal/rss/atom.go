@@ -0,0 +1,130 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Icon     string      `xml:"icon"`
+	Logo     string      `xml:"logo"`
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+	syndicationHints
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+// String returns the text construct's value, keeping the markup of xhtml content.
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink picks the rel="alternate" link, which is also the default when rel is omitted.
+// Links with any other rel, such as enclosures or replies, are never the page itself.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if rel := strings.TrimSpace(link.Rel); rel == "" || rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func (f *atomFeed) toRSSFeed() *RSSFeed {
+	feed := &RSSFeed{}
+	feed.Channel.Title = f.Title.String()
+	feed.Channel.Link = alternateLink(f.Links)
+	feed.Channel.Description = f.Subtitle.String()
+	feed.Channel.Language = f.Lang
+	feed.Refresh = parseRefreshHints("", nil, nil, f.syndicationHints)
+	feed.Image = strings.TrimSpace(f.Logo)
+	if feed.Image == "" {
+		feed.Image = strings.TrimSpace(f.Icon)
+	}
+	feed.Hub, feed.Self = hubLinks(f.Links)
+
+	for _, entry := range f.Entries {
+		item := RSSItem{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			PubDate:     entry.Published,
+			GUID:        strings.TrimSpace(entry.ID),
+		}
+		if item.Description == "" {
+			item.Description = entry.Content.String()
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+
+		for _, author := range entry.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				item.Authors = append(item.Authors, name)
+			} else if email := strings.TrimSpace(author.Email); email != "" {
+				item.Authors = append(item.Authors, email)
+			}
+		}
+
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				item.Categories = append(item.Categories, category.Label)
+			} else if category.Term != "" {
+				item.Categories = append(item.Categories, category.Term)
+			}
+		}
+
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" && link.Href != "" {
+				item.Enclosures = append(item.Enclosures, Enclosure{URL: link.Href, Type: link.Type, Length: parseLength(link.Length)})
+			}
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return feed
+}
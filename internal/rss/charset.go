@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var xmlEncodingPattern = regexp.MustCompile(`^\s*<\?xml[^>]*\bencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// toUTF8 transcodes a feed body to UTF-8. The Content-Type charset wins over
+// the XML declaration, and a document declaring neither is taken as UTF-8.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	label := contentTypeCharset(contentType)
+	if label == "" {
+		label = xmlDeclaredEncoding(body)
+	}
+	if label == "" || isUTF8Label(label) {
+		return body, nil
+	}
+
+	encoding, _ := charset.Lookup(label)
+	if encoding == nil {
+		return nil, fmt.Errorf("unsupported character set %q", label)
+	}
+	return encoding.NewDecoder().Bytes(body)
+}
+
+func contentTypeCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+func xmlDeclaredEncoding(body []byte) string {
+	head := body
+	if len(head) > 256 {
+		head = head[:256]
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	match := xmlEncodingPattern.FindSubmatch(head)
+	if match == nil {
+		return ""
+	}
+	return string(match[1])
+}
+
+func isUTF8Label(label string) bool {
+	label = strings.ToLower(label)
+	return label == "utf-8" || label == "utf8"
+}
+
+// newXMLDecoder reads an already-transcoded body, ignoring whatever encoding
+// its XML declaration still claims.
+func newXMLDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+func unmarshalXML(body []byte, v any) error {
+	return newXMLDecoder(body).Decode(v)
+}
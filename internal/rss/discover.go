@@ -0,0 +1,133 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// FeedLink is a feed candidate advertised by an HTML page.
+type FeedLink struct {
+	URL   string
+	Title string
+	Type  string
+
+	// Feed is the parsed feed when the URL given to DiscoverFeeds served
+	// one itself, so callers need not fetch it again. It is nil for links
+	// found on a page.
+	Feed *RSSFeed
+}
+
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+}
+
+// DiscoverFeeds fetches pageURL and returns the feeds it points to. If the URL
+// already serves a feed it is returned, parsed, as the only candidate; if it serves an
+// HTML page, its <link rel="alternate"> feed links are returned in page order.
+func DiscoverFeeds(ctx context.Context, fetcher Fetcher, pageURL string) ([]FeedLink, error) {
+	response, err := fetcher.Get(ctx, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	body := response.Body
+
+	contentType := response.Header.Get("Content-Type")
+	if !isHTML(contentType, body) {
+		feed, err := ParseFeed(body, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("error: %s is neither a feed nor an HTML page - %v", pageURL, err)
+		}
+		applyLinkHeader(feed, response.Header.Values("Link"))
+		resolveChannelLinks(feed, response.FinalURL)
+
+		// only a permanent redirect changes the feed's address; temporary
+		// ones may land on a CDN or tracking URL that should not be stored
+		feedURL := pageURL
+		if response.MovedTo != "" {
+			feedURL = response.MovedTo
+		}
+		return []FeedLink{{URL: feedURL, Feed: feed}}, nil
+	}
+
+	base, err := url.Parse(response.FinalURL)
+	if err != nil {
+		return nil, fmt.Errorf("error: invalid page url %s - %v", response.FinalURL, err)
+	}
+	links, err := findFeedLinks(body, contentType, base)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to parse HTML page - %v", err)
+	}
+	return links, nil
+}
+
+func isHTML(contentType string, body []byte) bool {
+	if contentType != "" {
+		return strings.Contains(strings.ToLower(contentType), "text/html")
+	}
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// findFeedLinks walks the document for feed <link> elements, resolving their
+// hrefs against the page's final URL.
+func findFeedLinks(body []byte, contentType string, base *url.URL) ([]FeedLink, error) {
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []FeedLink
+	seen := map[string]bool{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, linkType, href, title string
+			for _, attr := range n.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "type":
+					linkType = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				case "title":
+					title = attr.Val
+				}
+			}
+			if href != "" && feedLinkTypes[linkType] && hasToken(rel, "alternate") {
+				if resolved, err := base.Parse(href); err == nil && !seen[resolved.String()] {
+					seen[resolved.String()] = true
+					links = append(links, FeedLink{URL: resolved.String(), Title: title, Type: linkType})
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return links, nil
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package rss
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiscoverFeedsRedirects(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := newTestFetcher(t, server)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/feed", "/feed"},
+		{"/moved", "/feed"},
+		{"/temporary", "/temporary"},
+		{"/moved-then-temporary", "/temporary"},
+	}
+	for _, tt := range tests {
+		links, err := DiscoverFeeds(context.Background(), fetcher, server.URL+tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if len(links) != 1 || links[0].Feed == nil {
+			t.Errorf("%s: got %+v, want the parsed feed", tt.path, links)
+			continue
+		}
+		if links[0].URL != server.URL+tt.want {
+			t.Errorf("%s: URL = %q, want %q", tt.path, links[0].URL, server.URL+tt.want)
+		}
+	}
+}
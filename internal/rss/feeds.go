@@ -3,62 +3,286 @@ package rss
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
-	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// atom:link must precede link so it is not taken for the site link
+		AtomLinks   []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Language    string     `xml:"language"`
+		// itunes:image must precede image for the same reason
+		ITunesImage itunesImage  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		RawImage    channelImage `xml:"image"`
+		TTL         string       `xml:"ttl"`
+		SkipHours   []string     `xml:"skipHours>hour"`
+		SkipDays    []string     `xml:"skipDays>day"`
+		Item        []RSSItem    `xml:"item"`
+		syndicationHints
 	} `xml:"channel"`
+
+	// Image is the URL of the feed's logo or icon, whichever the format
+	// provides.
+	Image string `xml:"-"`
+
+	// Refresh is how often the publisher asks for the feed to be fetched.
+	Refresh RefreshHints `xml:"-"`
+
+	// Hub and Self are the WebSub hub and canonical topic URL the feed
+	// advertises, if any.
+	Hub  string `xml:"-"`
+	Self string `xml:"-"`
+}
+
+type channelImage struct {
+	URL string `xml:"url"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	RawAuthor   string   `xml:"author"`
+	GUID        string   `xml:"guid"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
+
+	RawEnclosures  []rssEnclosure   `xml:"enclosure"`
+	MediaContent   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup     []mediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	MediaThumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	ITunesDuration string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesImage    itunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+
+	// Authors are the names of the item's authors, whichever the format
+	// provides.
+	Authors    []string    `xml:"-"`
+	Enclosures []Enclosure `xml:"-"`
+}
+
+// Identity returns the value used to recognise an item across fetches of its
+// feed: the guid when the publisher provides one, otherwise the link, and
+// for items with neither, the title and date.
+func (item RSSItem) Identity() string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	return item.Title + "|" + item.PubDate
+}
+
+// authorName reduces the RSS 2.0 "email (Name)" author form to the name.
+func authorName(author string) string {
+	author = strings.TrimSpace(author)
+	open := strings.Index(author, "(")
+	if open > 0 && strings.HasSuffix(author, ")") && strings.Contains(author[:open], "@") {
+		return strings.TrimSpace(author[open+1 : len(author)-1])
+	}
+	return author
+}
+
+// nonEmpty returns the trimmed values that are not blank.
+func nonEmpty(values []string) []string {
+	var kept []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// CacheValidators are the response headers a server handed out for a feed,
+// sent back on the next fetch so unchanged feeds are not downloaded again.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchResult is the outcome of a conditional fetch. Feed is nil when the
+// server answered 304 Not Modified. FinalURL is where the request ended up
+// after any redirects; MovedTo is set only when the feed's address changed
+// through permanent redirects and should replace the stored URL.
+type FetchResult struct {
+	Feed        *RSSFeed
+	NotModified bool
+	Validators  CacheValidators
+	FinalURL    string
+	MovedTo     string
 }
 
 func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	client := &http.Client{}
+	result, err := FetchFeedConditional(ctx, DefaultFetcher, feedURL, CacheValidators{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Feed, nil
+}
 
-	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+// FetchFeedConditional fetches a feed with If-None-Match/If-Modified-Since
+// built from validators, reporting NotModified instead of a feed on a 304.
+func FetchFeedConditional(ctx context.Context, fetcher Fetcher, feedURL string, validators CacheValidators) (*FetchResult, error) {
+	headers := map[string]string{}
+	if validators.ETag != "" {
+		headers["If-None-Match"] = validators.ETag
+	}
+	if validators.LastModified != "" {
+		headers["If-Modified-Since"] = validators.LastModified
+	}
+
+	response, err := fetcher.Get(ctx, feedURL, headers)
 	if err != nil {
-		return nil, fmt.Errorf("error: failed to generate request - %v", err)
+		return nil, err
+	}
+
+	result := &FetchResult{
+		Validators: validators,
+		FinalURL:   response.FinalURL,
+		MovedTo:    response.MovedTo,
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
 	}
-	request.Header.Set("User-Agent", "gator")
 
-	response, err := client.Do(request)
+	feed, err := ParseFeed(response.Body, response.Header.Get("Content-Type"))
 	if err != nil {
-		return nil, fmt.Errorf("error: failed to receive response from server - %v", err)
+		return nil, err
 	}
+	applyLinkHeader(feed, response.Header.Values("Link"))
+	resolveChannelLinks(feed, response.FinalURL)
 
-	body, err := io.ReadAll(response.Body)
+	result.Feed = feed
+	result.Validators = CacheValidators{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	return result, nil
+}
+
+// resolveChannelLinks makes the site link and image absolute, as Atom and
+// JSON Feed publishers often give them relative to the feed.
+func resolveChannelLinks(feed *RSSFeed, feedURL string) {
+	base, err := url.Parse(feedURL)
 	if err != nil {
-		return nil, fmt.Errorf("error: failed to read response body - %v", err)
+		return
 	}
+	for _, link := range []*string{&feed.Channel.Link, &feed.Image} {
+		if *link == "" {
+			continue
+		}
+		if resolved, err := base.Parse(strings.TrimSpace(*link)); err == nil {
+			*link = resolved.String()
+		}
+	}
+}
 
-	feed := &RSSFeed{}
-	if err := xml.Unmarshal(body, feed); err != nil {
+// ParseFeed decodes a feed document that was obtained without FetchFeed,
+// such as content pushed by a WebSub hub.
+func ParseFeed(body []byte, contentType string) (*RSSFeed, error) {
+	body, err := toUTF8(body, contentType)
+	if err != nil {
 		return nil, fmt.Errorf("error: failed to decode response body - %v", err)
 	}
 
-	unescapeFields(feed)
+	feed, err := parseFeed(body, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to decode response body - %w", err)
+	}
 
 	return feed, nil
 }
 
-func unescapeFields(feed *RSSFeed) {
+// ErrNotFeed is returned for well-formed documents that are not a feed in
+// any supported format, such as HTML pages or unrelated XML and JSON.
+var ErrNotFeed = errors.New("document is not an RSS, Atom or JSON feed")
+
+// parseFeed decodes an RSS (0.9x, 1.0 or 2.0), Atom or JSON Feed document into the common RSSFeed model.
+func parseFeed(body []byte, contentType string) (*RSSFeed, error) {
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
+
+	root, err := rootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Local == "feed" && root.Space == atomNamespace {
+		atom := &atomFeed{}
+		if err := unmarshalXML(body, atom); err != nil {
+			return nil, err
+		}
+		return atom.toRSSFeed(), nil
+	}
+
+	if root.Local == "RDF" && root.Space == rdfNamespace {
+		rdf := &rdfFeed{}
+		if err := unmarshalXML(body, rdf); err != nil {
+			return nil, err
+		}
+		return rdf.toRSSFeed(), nil
+	}
+
+	if root.Local != "rss" {
+		return nil, fmt.Errorf("%w: unexpected root element <%s>", ErrNotFeed, root.Local)
+	}
+
+	feed := &RSSFeed{}
+	if err := unmarshalXML(body, feed); err != nil {
+		return nil, err
+	}
+	unescapeChannel(feed)
+	feed.Hub, feed.Self = hubLinks(feed.Channel.AtomLinks)
+	feed.Refresh = parseRefreshHints(feed.Channel.TTL, feed.Channel.SkipHours, feed.Channel.SkipDays, feed.Channel.syndicationHints)
+	feed.Image = strings.TrimSpace(feed.Channel.RawImage.URL)
+	if feed.Image == "" {
+		feed.Image = strings.TrimSpace(feed.Channel.ITunesImage.Href)
+	}
+	for i := range feed.Channel.Item {
+		item := &feed.Channel.Item[i]
+		if author := authorName(item.RawAuthor); author != "" {
+			item.Authors = []string{author}
+		} else {
+			item.Authors = nonEmpty(item.Creators)
+		}
+		collectEnclosures(item)
+	}
+	return feed, nil
+}
+
+func rootElement(body []byte) (xml.Name, error) {
+	decoder := newXMLDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// unescapeChannel decodes entities left in an RSS channel's title and
+// description, which publishers commonly escape twice. Item fields are
+// left as encoding/xml decoded them: descriptions are HTML that is decoded
+// again when rendered, and a second pass over titles and authors would turn
+// escaped text such as "&amp;lt;b&amp;gt;" into markup.
+func unescapeChannel(feed *RSSFeed) {
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for _, rssItem := range feed.Channel.Item {
-		rssItem.Title = html.UnescapeString(rssItem.Title)
-		rssItem.Description = html.UnescapeString(rssItem.Description)
-	}
 }
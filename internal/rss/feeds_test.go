@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+)
+
+const jsonAuthorsFeed = `{"version": "https://jsonfeed.org/version/1.1", "authors": [{"name": "Feed Author"}],
+"items": [{"id": "1"}, {"id": "2", "authors": [{"name": "Ann Lee"}, {"name": "Bo"}]}]}`
+
+func TestParseFeedAuthors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		item        int
+		want        []string
+	}{
+		{
+			"rss author",
+			"application/rss+xml",
+			`<rss version="2.0"><channel><item><author>ann@example.com (Ann Lee)</author><dc:creator xmlns:dc="http://purl.org/dc/elements/1.1/">Ignored</dc:creator></item></channel></rss>`,
+			0,
+			[]string{"Ann Lee"},
+		},
+		{
+			"rss creators",
+			"application/rss+xml",
+			`<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><item><dc:creator>Ann Lee</dc:creator><dc:creator> Bo, Jr. </dc:creator></item></channel></rss>`,
+			0,
+			[]string{"Ann Lee", "Bo, Jr."},
+		},
+		{
+			"atom",
+			"application/atom+xml",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><entry><author><name>Ann Lee</name></author><author><email>bo@example.com</email></author></entry></feed>`,
+			0,
+			[]string{"Ann Lee", "bo@example.com"},
+		},
+		{
+			"json feed author",
+			"application/feed+json",
+			jsonAuthorsFeed,
+			1,
+			[]string{"Ann Lee", "Bo"},
+		},
+		{
+			"json feed fallback",
+			"application/feed+json",
+			jsonAuthorsFeed,
+			0,
+			[]string{"Feed Author"},
+		},
+	}
+	for _, tt := range tests {
+		feed, err := ParseFeed([]byte(tt.body), tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := feed.Channel.Item[tt.item].Authors; !slices.Equal(got, tt.want) {
+			t.Errorf("%s: authors = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAtomEntryLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		links string
+		want  string
+	}{
+		{"alternate", `<link rel="enclosure" href="https://example.com/a.mp3"/><link rel="alternate" href="https://example.com/a"/>`, "https://example.com/a"},
+		{"no rel", `<link rel="replies" href="https://example.com/a/comments"/><link href="https://example.com/a"/>`, "https://example.com/a"},
+		{"enclosure only", `<link rel="enclosure" type="audio/mpeg" href="https://example.com/a.mp3"/>`, ""},
+		{"self only", `<link rel="self" href="https://example.com/a.atom"/>`, ""},
+	}
+	for _, tt := range tests {
+		body := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>1</id>` + tt.links + `</entry></feed>`
+		feed, err := ParseFeed([]byte(body), "application/atom+xml")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := feed.Channel.Item[0].Link; got != tt.want {
+			t.Errorf("%s: link = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFeedEscapedText(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		channel     string
+		item        string
+	}{
+		{
+			"rss",
+			"application/rss+xml",
+			`<rss version="2.0"><channel><title>News &amp;amp; Notes</title><item><title>Using &amp;lt;b&amp;gt; tags</title></item></channel></rss>`,
+			"News & Notes",
+			"Using &lt;b&gt; tags",
+		},
+		{
+			"atom",
+			"application/atom+xml",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><title type="text">A &amp;amp; B</title><entry><id>1</id><title type="text">A &amp;amp; B</title></entry></feed>`,
+			"A &amp; B",
+			"A &amp; B",
+		},
+	}
+	for _, tt := range tests {
+		feed, err := ParseFeed([]byte(tt.body), tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := feed.Channel.Title; got != tt.channel {
+			t.Errorf("%s: channel title = %q, want %q", tt.name, got, tt.channel)
+		}
+		if got := feed.Channel.Item[0].Title; got != tt.item {
+			t.Errorf("%s: item title = %q, want %q", tt.name, got, tt.item)
+		}
+	}
+}
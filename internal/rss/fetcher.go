@@ -0,0 +1,204 @@
+package rss
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Fetcher retrieves documents for the rss package. HTTPFetcher is the real
+// implementation; tests can supply one backed by httptest or local files.
+type Fetcher interface {
+	Get(ctx context.Context, rawURL string, headers map[string]string) (*Response, error)
+}
+
+// Response is a fetched document whose body has been fully read. Body is nil
+// for 304 Not Modified. A non-2xx status is reported as a *StatusError
+// alongside the response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// FinalURL is where the request ended up after redirects.
+	FinalURL string
+	// MovedTo is the URL reached by following only permanent (301/308)
+	// redirects from the requested URL, or empty if there were none.
+	MovedTo string
+}
+
+// FetchOptions configures an HTTPFetcher. Client, when set, is used as the
+// base client so callers can supply their own transport; the proxy and TLS
+// settings only apply to the default transport.
+type FetchOptions struct {
+	Client             *http.Client
+	Timeout            time.Duration
+	MaxBodySize        int64
+	UserAgent          string
+	ProxyURL           string
+	InsecureSkipVerify bool
+}
+
+var DefaultFetchOptions = FetchOptions{
+	Timeout:     30 * time.Second,
+	MaxBodySize: 10 << 20,
+	UserAgent:   "gator",
+}
+
+// HTTPFetcher fetches over HTTP with a timeout, a body size limit and
+// redirect tracking.
+type HTTPFetcher struct {
+	client      *http.Client
+	userAgent   string
+	maxBodySize int64
+}
+
+var DefaultFetcher Fetcher = mustNewHTTPFetcher(DefaultFetchOptions)
+
+func NewHTTPFetcher(options FetchOptions) (*HTTPFetcher, error) {
+	client, err := NewHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := options.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultFetchOptions.UserAgent
+	}
+
+	return &HTTPFetcher{
+		client:      client,
+		userAgent:   userAgent,
+		maxBodySize: options.MaxBodySize,
+	}, nil
+}
+
+// NewHTTPClient builds the client an HTTPFetcher with the same options would
+// use, for other requests that should go out the same way: through the
+// proxy, with the TLS and timeout settings, and with the User-Agent on
+// requests that do not set one.
+func NewHTTPClient(options FetchOptions) (*http.Client, error) {
+	client := &http.Client{}
+	if options.Client != nil {
+		base := *options.Client
+		client = &base
+	} else {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if options.ProxyURL != "" {
+			proxy, err := url.Parse(options.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("error: invalid proxy url %q - %v", options.ProxyURL, err)
+			}
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+		if options.InsecureSkipVerify {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		client.Transport = transport
+	}
+	if options.Timeout > 0 {
+		client.Timeout = options.Timeout
+	}
+
+	userAgent := options.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultFetchOptions.UserAgent
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = userAgentTransport{base: base, userAgent: userAgent}
+	return client, nil
+}
+
+// userAgentTransport sets a default User-Agent on outgoing requests.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("User-Agent") == "" {
+		request = request.Clone(request.Context())
+		request.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(request)
+}
+
+func mustNewHTTPFetcher(options FetchOptions) *HTTPFetcher {
+	fetcher, err := NewHTTPFetcher(options)
+	if err != nil {
+		panic(err)
+	}
+	return fetcher
+}
+
+// Get performs a GET with the configured User-Agent and the given extra
+// headers. The body is always closed.
+func (f *HTTPFetcher) Get(ctx context.Context, rawURL string, headers map[string]string) (*Response, error) {
+	result := &Response{}
+	permanent := true
+
+	// copy the client so redirect tracking is local to this request
+	client := *f.client
+	checkRedirect := f.client.CheckRedirect
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if checkRedirect != nil {
+			if err := checkRedirect(request, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		status := request.Response.StatusCode
+		permanent = permanent && (status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect)
+		if permanent {
+			result.MovedTo = request.URL.String()
+		}
+		return nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to generate request - %v", err)
+	}
+	request.Header.Set("User-Agent", f.userAgent)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error: failed to receive response from server - %w", err)
+	}
+	defer response.Body.Close()
+
+	result.StatusCode = response.StatusCode
+	result.Header = response.Header
+	result.FinalURL = response.Request.URL.String()
+	if result.MovedTo == rawURL {
+		result.MovedTo = ""
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		return result, nil
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return result, &StatusError{
+			URL:        rawURL,
+			StatusCode: response.StatusCode,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
+	}
+
+	result.Body, err = readLimited(response.Body, f.maxBodySize)
+	if err != nil {
+		return result, fmt.Errorf("error: failed to read response body - %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title><link>/</link>
+<item><title>First</title><link>/first</link><guid>1</guid></item>
+</channel></rss>`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	})
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/permanent", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/moved-then-temporary", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestFetcher(t *testing.T, server *httptest.Server) Fetcher {
+	t.Helper()
+	fetcher, err := NewHTTPFetcher(FetchOptions{Client: server.Client(), Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fetcher
+}
+
+func TestFetchFeedConditional(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := newTestFetcher(t, server)
+
+	result, err := FetchFeedConditional(context.Background(), fetcher, server.URL+"/feed", CacheValidators{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NotModified || result.Feed == nil {
+		t.Fatalf("got NotModified %v, feed %v; want a feed", result.NotModified, result.Feed)
+	}
+	if got := result.Feed.Channel.Title; got != "Test" {
+		t.Errorf("title = %q, want %q", got, "Test")
+	}
+	if got := result.Feed.Channel.Link; got != server.URL+"/" {
+		t.Errorf("site link = %q, want it resolved to %q", got, server.URL+"/")
+	}
+	if result.Validators.ETag != `"v1"` || result.Validators.LastModified == "" {
+		t.Errorf("validators = %+v, want the response's ETag and Last-Modified", result.Validators)
+	}
+
+	result, err = FetchFeedConditional(context.Background(), fetcher, server.URL+"/feed", result.Validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NotModified || result.Feed != nil {
+		t.Errorf("got NotModified %v, feed %v; want 304 Not Modified", result.NotModified, result.Feed)
+	}
+	if result.Validators.ETag != `"v1"` {
+		t.Errorf("validators = %+v, want the ones sent kept", result.Validators)
+	}
+}
+
+func TestFetchFeedConditionalRedirects(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := newTestFetcher(t, server)
+
+	tests := []struct {
+		path    string
+		movedTo string
+	}{
+		{"/moved", "/feed"},
+		{"/permanent", "/feed"},
+		{"/temporary", ""},
+		// only the permanent hops before the first temporary one count
+		{"/moved-then-temporary", "/temporary"},
+	}
+	for _, tt := range tests {
+		result, err := FetchFeedConditional(context.Background(), fetcher, server.URL+tt.path, CacheValidators{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		want := ""
+		if tt.movedTo != "" {
+			want = server.URL + tt.movedTo
+		}
+		if result.MovedTo != want {
+			t.Errorf("%s: MovedTo = %q, want %q", tt.path, result.MovedTo, want)
+		}
+		if result.FinalURL != server.URL+"/feed" {
+			t.Errorf("%s: FinalURL = %q, want %q", tt.path, result.FinalURL, server.URL+"/feed")
+		}
+	}
+}
+
+func TestFetchFeedConditionalStatusError(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := newTestFetcher(t, server)
+
+	tests := []struct {
+		path       string
+		status     int
+		retryAfter time.Duration
+		temporary  bool
+	}{
+		{"/missing", http.StatusNotFound, 0, false},
+		{"/busy", http.StatusServiceUnavailable, 2 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		_, err := FetchFeedConditional(context.Background(), fetcher, server.URL+tt.path, CacheValidators{})
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Errorf("%s: got %v, want a *StatusError", tt.path, err)
+			continue
+		}
+		if statusErr.StatusCode != tt.status || statusErr.RetryAfter != tt.retryAfter {
+			t.Errorf("%s: got status %d, retry after %v; want %d, %v", tt.path, statusErr.StatusCode, statusErr.RetryAfter, tt.status, tt.retryAfter)
+		}
+		if IsTemporary(err) != tt.temporary {
+			t.Errorf("%s: IsTemporary = %v, want %v", tt.path, IsTemporary(err), tt.temporary)
+		}
+	}
+}
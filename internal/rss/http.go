@@ -0,0 +1,100 @@
+package rss
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var ErrBodyTooLarge = errors.New("response body exceeds maximum size")
+
+// StatusError is returned for non-2xx responses. RetryAfter carries the
+// server's Retry-After hint and is zero when none was sent.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("error: %s returned %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %v)", e.RetryAfter)
+	}
+	return msg
+}
+
+// Temporary reports whether the same request may succeed if retried later.
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// IsTemporary reports whether a failed fetch is worth retrying soon: network
+// failures, timeouts, truncated responses and the statuses StatusError
+// considers temporary. Everything else, such as other 4xx responses,
+// documents that are not feeds, oversized bodies, bad certificates and
+// robots.txt refusals, will keep failing until the feed or its publisher
+// changes.
+func IsTemporary(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var backoffErr *HostBackoffError
+	if errors.As(err, &backoffErr) {
+		return true
+	}
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.Is(err, ErrBodyTooLarge), errors.Is(err, ErrNotFeed), errors.Is(err, ErrDisallowedByRobots):
+		return false
+	case errors.As(err, &certErr):
+		return false
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	// *url.Error from the client is a net.Error, covering refused
+	// connections, DNS failures and resets as well as timeouts
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+// parseRetryAfter accepts both forms of Retry-After: delay seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait.Round(time.Second)
+		}
+	}
+	return 0
+}
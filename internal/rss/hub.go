@@ -0,0 +1,46 @@
+package rss
+
+import "strings"
+
+// hubLinks picks the WebSub rel="hub" and rel="self" links out of a feed's
+// Atom links.
+func hubLinks(links []atomLink) (hub, self string) {
+	for _, link := range links {
+		switch {
+		case hasToken(link.Rel, "hub") && hub == "":
+			hub = strings.TrimSpace(link.Href)
+		case hasToken(link.Rel, "self") && self == "":
+			self = strings.TrimSpace(link.Href)
+		}
+	}
+	return hub, self
+}
+
+// applyLinkHeader lets hub and self links sent in HTTP Link headers, which
+// WebSub gives precedence, override those in the document.
+func applyLinkHeader(feed *RSSFeed, values []string) {
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			target, params, found := strings.Cut(strings.TrimSpace(part), ";")
+			target = strings.TrimSpace(target)
+			if !found || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+
+			for _, param := range strings.Split(params, ";") {
+				key, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.ToLower(strings.TrimSpace(key)) != "rel" {
+					continue
+				}
+				rel = strings.ToLower(strings.Trim(strings.TrimSpace(rel), `"`))
+				if hasToken(rel, "hub") {
+					feed.Hub = target
+				}
+				if hasToken(rel, "self") {
+					feed.Self = target
+				}
+			}
+		}
+	}
+}
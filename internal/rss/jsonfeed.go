@@ -0,0 +1,153 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Language    string         `json:"language"`
+	Author      *jsonAuthor    `json:"author"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Image         string           `json:"image"`
+	Tags          []string         `json:"tags"`
+	Author        *jsonAuthor      `json:"author"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Attachments   []jsonAttachment `json:"attachments"`
+}
+
+type jsonAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// isJSONFeed reports whether a response should be decoded as JSON Feed,
+// trusting the Content-Type first and falling back to sniffing the body.
+func isJSONFeed(contentType string, body []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "application/feed+json") || strings.Contains(contentType, "application/json") {
+		return true
+	}
+	trimmed := strings.TrimSpace(string(body))
+	return strings.HasPrefix(trimmed, "{")
+}
+
+// authorNames merges the 1.0 "author" object with the 1.1 "authors" array.
+func authorNames(author *jsonAuthor, authors []jsonAuthor) []string {
+	var names []string
+	if author != nil && author.Name != "" {
+		names = append(names, author.Name)
+	}
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// jsonItemID reads the item id, which the spec requires to be a string but
+// some publishers emit as a number.
+func jsonItemID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String()
+	}
+	return ""
+}
+
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	var f jsonFeed
+	if err := json.Unmarshal(body, &f); err != nil {
+		return nil, err
+	}
+	// the version URL is required and is all that sets a JSON Feed apart
+	if !strings.Contains(f.Version, "jsonfeed.org/version/") {
+		return nil, fmt.Errorf("%w: JSON document without a JSON Feed version", ErrNotFeed)
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = f.HomePageURL
+	feed.Channel.Description = f.Description
+	feed.Channel.Language = f.Language
+	feed.Image = f.Icon
+	if feed.Image == "" {
+		feed.Image = f.Favicon
+	}
+
+	feedAuthor := authorNames(f.Author, f.Authors)
+	for _, entry := range f.Items {
+		item := RSSItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			Description: entry.ContentHTML,
+			PubDate:     entry.DatePublished,
+			Authors:     authorNames(entry.Author, entry.Authors),
+			GUID:        jsonItemID(entry.ID),
+			Content:     entry.ContentHTML,
+			Categories:  entry.Tags,
+		}
+		if item.Link == "" {
+			item.Link = entry.ExternalURL
+		}
+		if item.Content == "" {
+			item.Content = entry.ContentText
+		}
+		if item.Description == "" {
+			item.Description = entry.ContentText
+		}
+		if item.Description == "" {
+			item.Description = entry.Summary
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+		if len(item.Authors) == 0 {
+			item.Authors = feedAuthor
+		}
+		for _, attachment := range entry.Attachments {
+			item.Enclosures = append(item.Enclosures, Enclosure{
+				URL:       attachment.URL,
+				Type:      attachment.MimeType,
+				Length:    attachment.SizeInBytes,
+				Duration:  time.Duration(attachment.DurationInSeconds * float64(time.Second)).Round(time.Second),
+				Thumbnail: entry.Image,
+			})
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+	return feed, nil
+}
@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enclosure is a media attachment of an item, merged from <enclosure>,
+// the iTunes podcast tags and Media RSS.
+type Enclosure struct {
+	URL       string
+	Type      string
+	Length    int64
+	Duration  time.Duration
+	Thumbnail string
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type mediaContent struct {
+	URL       string           `xml:"url,attr"`
+	Type      string           `xml:"type,attr"`
+	FileSize  string           `xml:"fileSize,attr"`
+	Duration  string           `xml:"duration,attr"`
+	Thumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type mediaGroup struct {
+	Content   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// collectEnclosures fills Enclosures from the raw attachment elements of an
+// RSS 2.0 item. Item-level durations and thumbnails apply to every
+// attachment that does not carry its own.
+func collectEnclosures(item *RSSItem) {
+	thumbnail := item.ITunesImage.Href
+	if thumbnail == "" {
+		thumbnail = firstThumbnail(item.MediaThumbnail)
+	}
+	duration := parseMediaDuration(item.ITunesDuration)
+
+	seen := map[string]bool{}
+	add := func(enclosure Enclosure) {
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			return
+		}
+		seen[enclosure.URL] = true
+		if enclosure.Duration == 0 {
+			enclosure.Duration = duration
+		}
+		if enclosure.Thumbnail == "" {
+			enclosure.Thumbnail = thumbnail
+		}
+		item.Enclosures = append(item.Enclosures, enclosure)
+	}
+
+	for _, raw := range item.RawEnclosures {
+		add(Enclosure{URL: strings.TrimSpace(raw.URL), Type: raw.Type, Length: parseLength(raw.Length)})
+	}
+
+	contents := item.MediaContent
+	for _, group := range item.MediaGroup {
+		if thumbnail == "" {
+			thumbnail = firstThumbnail(group.Thumbnail)
+		}
+		contents = append(contents, group.Content...)
+	}
+	for _, content := range contents {
+		add(Enclosure{
+			URL:       strings.TrimSpace(content.URL),
+			Type:      content.Type,
+			Length:    parseLength(content.FileSize),
+			Duration:  parseMediaDuration(content.Duration),
+			Thumbnail: firstThumbnail(content.Thumbnail),
+		})
+	}
+}
+
+func firstThumbnail(thumbnails []mediaThumbnail) string {
+	for _, thumbnail := range thumbnails {
+		if thumbnail.URL != "" {
+			return thumbnail.URL
+		}
+	}
+	return ""
+}
+
+func parseLength(value string) int64 {
+	length, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || length < 0 {
+		return 0
+	}
+	return length
+}
+
+// parseMediaDuration accepts plain seconds ("3723", "3723.5") as well as the
+// iTunes clock forms "MM:SS" and "HH:MM:SS".
+func parseMediaDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
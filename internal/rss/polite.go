@@ -0,0 +1,256 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PolitenessOptions configures a PoliteFetcher.
+type PolitenessOptions struct {
+	// Delay is the least time between the starts of two requests to the
+	// same host. A longer robots.txt Crawl-delay takes precedence.
+	Delay time.Duration
+	// MaxPerHost caps the requests in flight to one host.
+	MaxPerHost int
+	// CheckRobots makes the fetcher obey robots.txt for UserAgent.
+	CheckRobots bool
+	UserAgent   string
+	// Logf, when set, is told about every request that is delayed or
+	// refused and why.
+	Logf func(format string, args ...any)
+}
+
+var DefaultPolitenessOptions = PolitenessOptions{
+	Delay:      time.Second,
+	MaxPerHost: 2,
+	UserAgent:  DefaultFetchOptions.UserAgent,
+}
+
+const (
+	robotsCacheTTL = 24 * time.Hour
+	// robots.txt files that could not be fetched are tried again sooner
+	robotsRetryTTL = time.Hour
+	// how long to leave a host alone after a 429 or 503 without Retry-After
+	defaultRetryAfter = time.Minute
+	maxRetryAfter     = 24 * time.Hour
+)
+
+// ErrDisallowedByRobots is returned for URLs robots.txt does not let us fetch.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// HostBackoffError is returned, without contacting the host, while a host's
+// Retry-After from an earlier 429 or 503 response has not yet passed.
+type HostBackoffError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostBackoffError) Error() string {
+	return fmt.Sprintf("error: %s asked not to be contacted until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+func (e *HostBackoffError) Temporary() bool {
+	return true
+}
+
+// PoliteFetcher wraps a Fetcher so that all requests through it share
+// per-host limits: a minimum delay between requests, a cap on concurrent
+// requests, optional robots.txt checks, and a pause after the host answers
+// 429 or 503.
+type PoliteFetcher struct {
+	fetcher Fetcher
+	options PolitenessOptions
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+
+	// guarded by PoliteFetcher.mu
+	next         time.Time
+	blockedUntil time.Time
+
+	robotsMu      sync.Mutex
+	robots        *robotsRules
+	robotsExpires time.Time
+}
+
+func NewPoliteFetcher(fetcher Fetcher, options PolitenessOptions) *PoliteFetcher {
+	if options.MaxPerHost < 1 {
+		options.MaxPerHost = 1
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = DefaultPolitenessOptions.UserAgent
+	}
+	return &PoliteFetcher{
+		fetcher: fetcher,
+		options: options,
+		hosts:   map[string]*hostState{},
+	}
+}
+
+func (f *PoliteFetcher) Get(ctx context.Context, rawURL string, headers map[string]string) (*Response, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return f.fetcher.Get(ctx, rawURL, headers)
+	}
+	hostname := strings.ToLower(target.Host)
+	host := f.host(hostname)
+
+	f.mu.Lock()
+	blockedUntil := host.blockedUntil
+	f.mu.Unlock()
+	if time.Now().Before(blockedUntil) {
+		f.logf("Skipping %s: %s asked to wait until %s", rawURL, hostname, blockedUntil.Format(time.RFC3339))
+		return nil, &HostBackoffError{Host: hostname, Until: blockedUntil}
+	}
+
+	select {
+	case host.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-host.slots }()
+
+	delay := f.options.Delay
+	if f.options.CheckRobots {
+		rules, err := f.robotsRules(ctx, target, host, hostname)
+		if err != nil {
+			return nil, err
+		}
+		if !rules.allowed(target.EscapedPath() + querySuffix(target)) {
+			f.logf("Not fetching %s: disallowed by robots.txt for %s", rawURL, f.options.UserAgent)
+			return nil, fmt.Errorf("error: %s - %w", rawURL, ErrDisallowedByRobots)
+		}
+		if rules.crawlDelay > delay {
+			delay = rules.crawlDelay
+		}
+	}
+
+	if err := f.wait(ctx, host, hostname, delay); err != nil {
+		return nil, err
+	}
+
+	response, err := f.fetcher.Get(ctx, rawURL, headers)
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable) {
+		pause := statusErr.RetryAfter
+		if pause <= 0 {
+			pause = defaultRetryAfter
+		}
+		if pause > maxRetryAfter {
+			pause = maxRetryAfter
+		}
+		until := time.Now().Add(pause)
+
+		f.mu.Lock()
+		if until.After(host.blockedUntil) {
+			host.blockedUntil = until
+		}
+		f.mu.Unlock()
+		f.logf("%s answered %d; leaving it alone for %v", hostname, statusErr.StatusCode, pause)
+	}
+	return response, err
+}
+
+func (f *PoliteFetcher) host(hostname string) *hostState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	host, ok := f.hosts[hostname]
+	if !ok {
+		host = &hostState{slots: make(chan struct{}, f.options.MaxPerHost)}
+		f.hosts[hostname] = host
+	}
+	return host
+}
+
+// wait reserves the host's next request start, at least delay after the
+// previous one, and sleeps until it comes round.
+func (f *PoliteFetcher) wait(ctx context.Context, host *hostState, hostname string, delay time.Duration) error {
+	now := time.Now()
+	f.mu.Lock()
+	start := host.next
+	if start.Before(now) {
+		start = now
+	}
+	host.next = start.Add(delay)
+	f.mu.Unlock()
+
+	pause := start.Sub(now)
+	if pause <= 0 {
+		return nil
+	}
+	f.logf("Waiting %v before the next request to %s", pause.Round(time.Millisecond), hostname)
+
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// robotsRules returns the host's cached robots.txt rules, fetching them when
+// missing or stale. A robots.txt that is missing or cannot be fetched
+// allows everything.
+func (f *PoliteFetcher) robotsRules(ctx context.Context, target *url.URL, host *hostState, hostname string) (*robotsRules, error) {
+	host.robotsMu.Lock()
+	defer host.robotsMu.Unlock()
+	if host.robots != nil && time.Now().Before(host.robotsExpires) {
+		return host.robots, nil
+	}
+
+	if err := f.wait(ctx, host, hostname, f.options.Delay); err != nil {
+		return nil, err
+	}
+
+	robotsURL := (&url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}).String()
+	response, err := f.fetcher.Get(ctx, robotsURL, nil)
+
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		host.robots = parseRobots(response.Body, userAgentProduct(f.options.UserAgent))
+		host.robotsExpires = time.Now().Add(robotsCacheTTL)
+	case errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 && statusErr.StatusCode != http.StatusTooManyRequests:
+		host.robots = &robotsRules{}
+		host.robotsExpires = time.Now().Add(robotsCacheTTL)
+	default:
+		f.logf("Could not fetch %s, assuming everything is allowed: %v", robotsURL, err)
+		host.robots = &robotsRules{}
+		host.robotsExpires = time.Now().Add(robotsRetryTTL)
+	}
+	return host.robots, nil
+}
+
+// userAgentProduct is the name robots.txt groups are matched against: the
+// User-Agent up to its first "/" or space.
+func userAgentProduct(userAgent string) string {
+	product, _, _ := strings.Cut(userAgent, " ")
+	product, _, _ = strings.Cut(product, "/")
+	return product
+}
+
+func querySuffix(target *url.URL) string {
+	if target.RawQuery == "" {
+		return ""
+	}
+	return "?" + target.RawQuery
+}
+
+func (f *PoliteFetcher) logf(format string, args ...any) {
+	if f.options.Logf != nil {
+		f.options.Logf(format, args...)
+	}
+}
@@ -0,0 +1,55 @@
+package rss
+
+import "encoding/xml"
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// rdfFeed covers RSS 0.90 and 1.0, where items are siblings of the channel
+// rather than its children.
+type rdfFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		syndicationHints
+	} `xml:"channel"`
+	Image channelImage `xml:"image"`
+	Items []rdfItem    `xml:"item"`
+}
+
+type rdfItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func (f *rdfFeed) toRSSFeed() *RSSFeed {
+	feed := &RSSFeed{}
+	feed.Channel.Title = f.Channel.Title
+	feed.Channel.Link = f.Channel.Link
+	feed.Channel.Description = f.Channel.Description
+	feed.Channel.Language = f.Channel.Language
+	feed.Refresh = parseRefreshHints("", nil, nil, f.Channel.syndicationHints)
+	feed.Image = f.Image.URL
+
+	for _, entry := range f.Items {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Description,
+			PubDate:     entry.Date,
+			Authors:     nonEmpty(entry.Creators),
+			GUID:        entry.About,
+			Content:     entry.Content,
+			Categories:  entry.Subjects,
+		})
+	}
+	return feed
+}
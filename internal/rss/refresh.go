@@ -0,0 +1,104 @@
+package rss
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRefreshInterval caps the interval a feed can ask for, so a careless
+// hint cannot keep a feed from being fetched for weeks.
+const MaxRefreshInterval = 24 * time.Hour
+
+// RefreshHints are the publisher's requests about when a feed should be
+// polled. The zero value asks for nothing.
+type RefreshHints struct {
+	// Interval is the least time between fetches, from <ttl> or the
+	// syndication module's updatePeriod and updateFrequency.
+	Interval time.Duration
+	// SkipHours are the hours of the day, in UTC, not to fetch in.
+	SkipHours []int
+	// SkipDays are the days of the week, in UTC, not to fetch on.
+	SkipDays []time.Weekday
+}
+
+// syndicationHints are the sy:* channel elements.
+type syndicationHints struct {
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// interval is the update period divided by the frequency, which defaults to
+// once per period.
+func (h syndicationHints) interval() time.Duration {
+	period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(h.UpdatePeriod))]
+	if !ok {
+		return 0
+	}
+	frequency, err := strconv.Atoi(strings.TrimSpace(h.UpdateFrequency))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+	return period / time.Duration(frequency)
+}
+
+// parseRefreshHints builds hints from the raw channel values, ignoring any
+// that are malformed. When both ttl and the syndication module are present
+// the longer interval wins.
+func parseRefreshHints(ttl string, skipHours, skipDays []string, sy syndicationHints) RefreshHints {
+	var hints RefreshHints
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(ttl)); err == nil && minutes > 0 {
+		hints.Interval = time.Duration(minutes) * time.Minute
+	}
+	if interval := sy.interval(); interval > hints.Interval {
+		hints.Interval = interval
+	}
+	if hints.Interval > MaxRefreshInterval {
+		hints.Interval = MaxRefreshInterval
+	}
+
+	hours := map[int]bool{}
+	for _, value := range skipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// some publishers count 1-24 rather than 0-23
+		hours[hour%24] = true
+	}
+	// a feed that skips every hour would never be fetched
+	if len(hours) < 24 {
+		for hour := range hours {
+			hints.SkipHours = append(hints.SkipHours, hour)
+		}
+		sort.Ints(hints.SkipHours)
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, value := range skipDays {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(strings.TrimSpace(value), day.String()) {
+				days[day] = true
+			}
+		}
+	}
+	if len(days) < 7 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if days[day] {
+				hints.SkipDays = append(hints.SkipDays, day)
+			}
+		}
+	}
+
+	return hints
+}
@@ -0,0 +1,261 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subscription is a topic subscribed to at a hub. ID names the subscription
+// in its callback URL. Secret is only used with HTTPS hubs; see signed.
+type Subscription struct {
+	ID     string
+	Topic  string
+	Hub    string
+	Secret string
+}
+
+// signed reports whether the subscription's content must carry a signature.
+// The secret is only ever sent to a hub over HTTPS, as sending it in the
+// clear would give away the key signatures are checked with; content from
+// plain HTTP hubs is therefore accepted unsigned.
+func (sub Subscription) signed() bool {
+	hub, err := url.Parse(sub.Hub)
+	return sub.Secret != "" && err == nil && strings.EqualFold(hub.Scheme, "https")
+}
+
+// Store keeps subscription state for the callback handler and receives the
+// content hubs push.
+type Store interface {
+	// Lookup returns the subscription with the given ID, or ErrUnknownSubscription.
+	Lookup(ctx context.Context, id string) (Subscription, error)
+	// Verified records that the hub confirmed the subscription for lease.
+	Verified(ctx context.Context, id string, lease time.Duration) error
+	// Denied records that the hub refused or cancelled the subscription.
+	Denied(ctx context.Context, id string, reason string) error
+	// Deliver ingests a feed document pushed for the subscription. The hub
+	// waits for it to return, so slow work should happen elsewhere.
+	Deliver(ctx context.Context, id string, contentType string, body []byte) error
+}
+
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+const maxContentSize = 10 << 20
+
+// Subscriber asks hubs for subscriptions and serves the callback URL hubs
+// use to verify them and push content.
+type Subscriber struct {
+	callback *url.URL
+	client   *http.Client
+	store    Store
+}
+
+// NewSubscriber creates a subscriber whose callback URLs live under
+// callbackURL, the publicly reachable address of its handler.
+func NewSubscriber(callbackURL string, client *http.Client, store Store) (*Subscriber, error) {
+	callback, err := url.Parse(callbackURL)
+	if err != nil || callback.Scheme == "" || callback.Host == "" {
+		return nil, fmt.Errorf("error: invalid websub callback url %q", callbackURL)
+	}
+	if !strings.HasSuffix(callback.Path, "/") {
+		callback.Path += "/"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Subscriber{callback: callback, client: client, store: store}, nil
+}
+
+// Path is the URL path the handler must be mounted on.
+func (s *Subscriber) Path() string {
+	return s.callback.Path
+}
+
+// CallbackURL is the address the hub notifies for the subscription id.
+func (s *Subscriber) CallbackURL(id string) string {
+	return s.callback.JoinPath(url.PathEscape(id)).String()
+}
+
+// Subscribe asks the hub to push updates of sub.Topic for lease.
+func (s *Subscriber) Subscribe(ctx context.Context, sub Subscription, lease time.Duration) error {
+	return s.request(ctx, "subscribe", sub, lease)
+}
+
+// request sends a subscription request to the hub. The hub only accepts it
+// here; the outcome arrives later as a verification request to the callback.
+func (s *Subscriber) request(ctx context.Context, mode string, sub Subscription, lease time.Duration) error {
+	form := url.Values{
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {s.CallbackURL(sub.ID)},
+	}
+	if lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(lease/time.Second)))
+	}
+	if sub.signed() {
+		form.Set("hub.secret", sub.Secret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error: failed to generate hub request - %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error: failed to reach hub %s - %v", sub.Hub, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("error: hub %s rejected %s request for %s - %d %s", sub.Hub, mode, sub.Topic, response.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := url.PathUnescape(strings.TrimPrefix(r.URL.Path, s.callback.Path))
+	if err != nil || id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	sub, err := s.store.Lookup(r.Context(), id)
+	if errors.Is(err, ErrUnknownSubscription) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "lookup failed", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, sub)
+	case http.MethodPost:
+		s.deliver(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers a hub's intent verification by echoing the challenge for
+// subscriptions we hold, and records denials.
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	query := r.URL.Query()
+	mode := query.Get("hub.mode")
+	topic := query.Get("hub.topic")
+
+	if topic != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch mode {
+	case "subscribe":
+		lease, _ := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err := s.store.Verified(r.Context(), sub.ID, time.Duration(lease)*time.Second); err != nil {
+			http.Error(w, "failed to record subscription", http.StatusInternalServerError)
+			return
+		}
+	case "denied":
+		if err := s.store.Denied(r.Context(), sub.ID, query.Get("hub.reason")); err != nil {
+			http.Error(w, "failed to record denial", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	case "unsubscribe":
+		// unwanted subscriptions are left to expire, so any unsubscribe
+		// request for a subscription we hold did not come from us
+		http.NotFound(w, r)
+		return
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, query.Get("hub.challenge"))
+}
+
+// deliver accepts pushed content. Content failing the signature check is
+// acknowledged but dropped, as the spec requires.
+func (s *Subscriber) deliver(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContentSize+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxContentSize {
+		http.Error(w, "content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if sub.signed() && !validSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := s.store.Deliver(r.Context(), sub.ID, r.Header.Get("Content-Type"), body); err != nil {
+		http.Error(w, "failed to ingest content", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func validSignature(secret, header string, body []byte) bool {
+	method, signature, found := strings.Cut(header, "=")
+	if !found {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// NewSecret returns a random secret for signing pushed content.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,294 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore records what the subscriber tells it.
+type memoryStore struct {
+	mu         sync.Mutex
+	subs       map[string]Subscription
+	leases     map[string]time.Duration
+	denials    map[string]string
+	deliveries map[string][]string
+}
+
+func newMemoryStore(subs ...Subscription) *memoryStore {
+	store := &memoryStore{
+		subs:       map[string]Subscription{},
+		leases:     map[string]time.Duration{},
+		denials:    map[string]string{},
+		deliveries: map[string][]string{},
+	}
+	for _, sub := range subs {
+		store.subs[sub.ID] = sub
+	}
+	return store
+}
+
+func (m *memoryStore) Lookup(ctx context.Context, id string) (Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return Subscription{}, ErrUnknownSubscription
+	}
+	return sub, nil
+}
+
+func (m *memoryStore) Verified(ctx context.Context, id string, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leases[id] = lease
+	return nil
+}
+
+func (m *memoryStore) Denied(ctx context.Context, id string, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.denials[id] = reason
+	return nil
+}
+
+func (m *memoryStore) Deliver(ctx context.Context, id string, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries[id] = append(m.deliveries[id], string(body))
+	return nil
+}
+
+func (m *memoryStore) lease(id string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leases[id]
+}
+
+func (m *memoryStore) denial(id string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reason, ok := m.denials[id]
+	return reason, ok
+}
+
+func (m *memoryStore) delivered(id string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deliveries[id]
+}
+
+// testHub stands in for a hub: it records subscription requests so the
+// test can play the hub's side of verification and delivery.
+type testHub struct {
+	*httptest.Server
+	requests chan url.Values
+}
+
+// newTestHub starts a hub served over HTTPS, or plain HTTP unless secure.
+func newTestHub(t *testing.T, secure bool) *testHub {
+	t.Helper()
+	hub := &testHub{requests: make(chan url.Values, 1)}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub.requests <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	})
+	if secure {
+		hub.Server = httptest.NewTLSServer(handler)
+	} else {
+		hub.Server = httptest.NewServer(handler)
+	}
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+// newTestSubscriber serves a subscriber for store, reaching hubs with
+// hubClient, and returns it with the client to call its callback URLs with.
+func newTestSubscriber(t *testing.T, store Store, hubClient *http.Client) (*Subscriber, *http.Client) {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	sub, err := NewSubscriber(server.URL+"/websub", hubClient, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle(sub.Path(), sub)
+	return sub, server.Client()
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyRequest(t *testing.T, client *http.Client, callback string, query url.Values) (int, string) {
+	t.Helper()
+	response, err := client.Get(callback + "?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	return response.StatusCode, string(body)
+}
+
+func deliverRequest(t *testing.T, client *http.Client, callback, signature, body string) int {
+	t.Helper()
+	request, err := http.NewRequest("POST", callback, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/rss+xml")
+	if signature != "" {
+		request.Header.Set("X-Hub-Signature", signature)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestSubscribeVerifyAndDeliver(t *testing.T) {
+	hub := newTestHub(t, true)
+	feed := Subscription{ID: "feed-1", Topic: "https://example.com/feed.xml", Hub: hub.URL, Secret: "s3cret"}
+	store := newMemoryStore(feed)
+	subscriber, client := newTestSubscriber(t, store, hub.Client())
+
+	if err := subscriber.Subscribe(context.Background(), feed, 10*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	form := <-hub.requests
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != feed.Topic || form.Get("hub.secret") != feed.Secret {
+		t.Fatalf("hub got %v, want a subscribe request for the topic with the secret", form)
+	}
+	if form.Get("hub.lease_seconds") != "864000" {
+		t.Errorf("hub.lease_seconds = %q, want %q", form.Get("hub.lease_seconds"), "864000")
+	}
+	callback := form.Get("hub.callback")
+	if callback != subscriber.CallbackURL(feed.ID) {
+		t.Fatalf("hub.callback = %q, want %q", callback, subscriber.CallbackURL(feed.ID))
+	}
+
+	status, body := verifyRequest(t, client, callback, url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {feed.Topic},
+		"hub.challenge":     {"challenge-123"},
+		"hub.lease_seconds": {"3600"},
+	})
+	if status != http.StatusOK || body != "challenge-123" {
+		t.Errorf("verification answered %d %q, want 200 echoing the challenge", status, body)
+	}
+	if lease := store.lease(feed.ID); lease != time.Hour {
+		t.Errorf("recorded lease %v, want %v", lease, time.Hour)
+	}
+
+	// a verification for another topic is not ours to confirm
+	status, body = verifyRequest(t, client, callback, url.Values{
+		"hub.mode":      {"subscribe"},
+		"hub.topic":     {"https://example.com/other.xml"},
+		"hub.challenge": {"challenge-456"},
+	})
+	if status != http.StatusNotFound || body == "challenge-456" {
+		t.Errorf("verification for another topic answered %d %q, want 404 without the challenge", status, body)
+	}
+
+	content := "<rss><channel><title>Pushed</title></channel></rss>"
+	if status := deliverRequest(t, client, callback, sign(feed.Secret, []byte(content)), content); status != http.StatusAccepted {
+		t.Errorf("signed delivery answered %d, want 202", status)
+	}
+	if got := store.delivered(feed.ID); len(got) != 1 || got[0] != content {
+		t.Errorf("delivered %q, want the signed content once", got)
+	}
+}
+
+func TestPlainHTTPHubUnsigned(t *testing.T) {
+	hub := newTestHub(t, false)
+	feed := Subscription{ID: "feed-1", Topic: "http://example.com/feed.xml", Hub: hub.URL, Secret: "s3cret"}
+	store := newMemoryStore(feed)
+	subscriber, client := newTestSubscriber(t, store, nil)
+
+	if err := subscriber.Subscribe(context.Background(), feed, 0); err != nil {
+		t.Fatal(err)
+	}
+	if form := <-hub.requests; form.Has("hub.secret") {
+		t.Errorf("hub.secret = %q sent over plain HTTP, want it withheld", form.Get("hub.secret"))
+	}
+
+	content := "<rss><channel><title>Unsigned</title></channel></rss>"
+	if status := deliverRequest(t, client, subscriber.CallbackURL(feed.ID), "", content); status != http.StatusAccepted {
+		t.Errorf("unsigned delivery answered %d, want 202", status)
+	}
+	if got := store.delivered(feed.ID); len(got) != 1 || got[0] != content {
+		t.Errorf("delivered %q, want the unsigned content once", got)
+	}
+}
+
+func TestDeliverBadSignature(t *testing.T) {
+	feed := Subscription{ID: "feed-1", Topic: "https://example.com/feed.xml", Hub: "https://hub.example.com/", Secret: "s3cret"}
+	store := newMemoryStore(feed)
+	subscriber, client := newTestSubscriber(t, store, nil)
+	callback := subscriber.CallbackURL(feed.ID)
+
+	content := "<rss><channel><title>Forged</title></channel></rss>"
+	for _, signature := range []string{
+		"",
+		sign("wrong secret", []byte(content)),
+		sign(feed.Secret, []byte("other content")),
+		"md5=00",
+	} {
+		if status := deliverRequest(t, client, callback, signature, content); status != http.StatusAccepted {
+			t.Errorf("delivery signed %q answered %d, want 202", signature, status)
+		}
+	}
+	if got := store.delivered(feed.ID); len(got) != 0 {
+		t.Errorf("delivered %q, want badly signed content dropped", got)
+	}
+}
+
+func TestVerifyDenied(t *testing.T) {
+	feed := Subscription{ID: "feed-1", Topic: "https://example.com/feed.xml"}
+	store := newMemoryStore(feed)
+	subscriber, client := newTestSubscriber(t, store, nil)
+
+	status, _ := verifyRequest(t, client, subscriber.CallbackURL(feed.ID), url.Values{
+		"hub.mode":   {"denied"},
+		"hub.topic":  {feed.Topic},
+		"hub.reason": {"not allowed"},
+	})
+	if status != http.StatusOK {
+		t.Errorf("denial answered %d, want 200", status)
+	}
+	if reason, ok := store.denial(feed.ID); !ok || reason != "not allowed" {
+		t.Errorf("recorded denial %q (%v), want %q", reason, ok, "not allowed")
+	}
+}
+
+func TestUnknownSubscription(t *testing.T) {
+	subscriber, client := newTestSubscriber(t, newMemoryStore(), nil)
+	status, _ := verifyRequest(t, client, subscriber.CallbackURL("missing"), url.Values{
+		"hub.mode":      {"subscribe"},
+		"hub.topic":     {"https://example.com/feed.xml"},
+		"hub.challenge": {"challenge"},
+	})
+	if status != http.StatusNotFound {
+		t.Errorf("verification of an unknown subscription answered %d, want 404", status)
+	}
+}
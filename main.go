@@ -1,25 +1,38 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	"github.com/notsoexpert/goblogaggregator/internal/config"
 	"github.com/notsoexpert/goblogaggregator/internal/database"
+	"github.com/notsoexpert/goblogaggregator/internal/extract"
+	"github.com/notsoexpert/goblogaggregator/internal/opml"
+	"github.com/notsoexpert/goblogaggregator/internal/pubdate"
+	"github.com/notsoexpert/goblogaggregator/internal/render"
 	"github.com/notsoexpert/goblogaggregator/internal/rss"
+	"github.com/notsoexpert/goblogaggregator/internal/websub"
 )
 
 type state struct {
 	Config    *config.Config
+	DB        *sql.DB
 	DBQueries *database.Queries
+	Fetcher   rss.Fetcher
 }
 
 type command struct {
@@ -42,58 +55,792 @@ func middlewareLoggedIn(handler func(s *state, cmd command, sqlUser database.Use
 	}
 }
 
-func parsePublishedTime(pubTime string) sql.NullTime {
-	validLayouts := []string{
-		time.RFC3339, time.ANSIC, time.Kitchen, time.Stamp, "Mon, 02 Jan 2006 15:04:05 -0700",
+// parsePublishedTime falls back to the time the item was first seen when the
+// feed's date cannot be parsed, so undated posts still sort sensibly.
+func parsePublishedTime(pubTime string, firstSeen time.Time) sql.NullTime {
+	if published, ok := pubdate.ParseAt(pubTime, firstSeen); ok {
+		return sql.NullTime{Time: published, Valid: true}
 	}
-	for _, layout := range validLayouts {
-		time, err := time.Parse(layout, pubTime)
-		if err == nil {
-			return sql.NullTime{Time: time, Valid: true}
+	return sql.NullTime{Time: firstSeen, Valid: true}
+}
+
+// newFetcher builds the feed fetcher from the optional fetch and politeness
+// settings in the config, falling back to the rss package defaults. Every
+// request goes through one shared PoliteFetcher so per-host limits hold
+// across feeds, articles and discovery.
+func newFetcher(cfg *config.Config) (rss.Fetcher, error) {
+	options, err := fetchOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher, err := rss.NewHTTPFetcher(options)
+	if err != nil {
+		return nil, err
+	}
+
+	politeness := rss.DefaultPolitenessOptions
+	if cfg.HostDelay != "" {
+		delay, err := time.ParseDuration(cfg.HostDelay)
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid host_delay %q in config - %v", cfg.HostDelay, err)
 		}
+		politeness.Delay = delay
 	}
-	return sql.NullTime{}
+	if cfg.HostConcurrency > 0 {
+		politeness.MaxPerHost = cfg.HostConcurrency
+	}
+	politeness.CheckRobots = cfg.CheckRobots
+	politeness.UserAgent = options.UserAgent
+	politeness.Logf = func(format string, args ...any) {
+		fmt.Printf(format+"\n", args...)
+	}
+
+	return rss.NewPoliteFetcher(fetcher, politeness), nil
 }
 
-func scrapeFeeds(s *state) error {
-	// get next feed
-	sqlFeed, err := s.DBQueries.GetNextFeedToFetch(context.Background())
+// fetchOptions reads the fetch settings from the config over the rss
+// package defaults.
+func fetchOptions(cfg *config.Config) (rss.FetchOptions, error) {
+	options := rss.DefaultFetchOptions
+	if cfg.FetchTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.FetchTimeout)
+		if err != nil {
+			return options, fmt.Errorf("error: invalid fetch_timeout %q in config - %v", cfg.FetchTimeout, err)
+		}
+		options.Timeout = timeout
+	}
+	if cfg.MaxFeedSize > 0 {
+		options.MaxBodySize = cfg.MaxFeedSize
+	}
+	if cfg.UserAgent != "" {
+		options.UserAgent = cfg.UserAgent
+	}
+	options.ProxyURL = cfg.ProxyURL
+	options.InsecureSkipVerify = cfg.InsecureSkipVerify
+
+	return options, nil
+}
+
+// scrapeFeeds runs one agg cycle: due feeds are claimed from the database
+// in batches and fetched by a pool of workers until none are left, then a
+// summary of the cycle is printed. Feeds claimed during the cycle are not
+// claimed again before the next one, however short their refresh interval.
+func scrapeFeeds(s *state, workers, batchSize int) error {
+	start := time.Now()
+	// feeds are stamped with the database's clock when claimed, so the
+	// cycle has to start by that clock too
+	cycleStart, err := s.DBQueries.GetDatabaseTime(context.Background())
 	if err != nil {
-		return fmt.Errorf("error: failed to get next feed from database - %v", err)
+		return fmt.Errorf("error: failed to read database time - %v", err)
+	}
+
+	// feeds a hub pushes to are only polled now and then, in case pushes
+	// stop arriving; without a callback URL nothing is pushed to us at all
+	pushFallback := websubPollFallback
+	if s.Config.WebsubCallbackURL == "" {
+		pushFallback = 0
+	}
+	feeds := make(chan database.Feed)
+	results := make(chan scrapeResult)
+
+	var claimErr error
+	go func() {
+		defer close(feeds)
+		for {
+			batch, err := s.DBQueries.ClaimFeedsToFetch(context.Background(), database.ClaimFeedsToFetchParams{
+				CycleStart:          cycleStart,
+				LeaseValidAt:        time.Now().UTC(),
+				PushFallbackMinutes: int32(pushFallback / time.Minute),
+				BatchSize:           int32(batchSize),
+			})
+			if err != nil {
+				claimErr = fmt.Errorf("error: failed to claim feeds to fetch - %v", err)
+				return
+			}
+			if len(batch) == 0 {
+				// every feed was fetched recently or asked to be skipped for now
+				return
+			}
+			for _, sqlFeed := range batch {
+				feeds <- sqlFeed
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sqlFeed := range feeds {
+				results <- scrapeFeed(s, sqlFeed)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	fetched, newPosts, failures := 0, 0, 0
+	for result := range results {
+		if result.err != nil {
+			fmt.Println(result.err)
+			failures++
+			continue
+		}
+		if result.fetched {
+			fetched++
+		}
+		newPosts += result.newPosts
 	}
+	fmt.Printf("Fetched %d feeds: %d new posts, %d errors in %v\n", fetched, newPosts, failures, time.Since(start).Round(time.Millisecond))
+
+	// the results channel is closed only after the claiming goroutine
+	// has returned, so claimErr is safe to read
+	return claimErr
+}
 
-	// mark as fetched
-	err = s.DBQueries.MarkFeedFetched(context.Background(), sqlFeed.ID)
+// scrapeResult is the outcome of scrapeFeed for one feed.
+type scrapeResult struct {
+	fetched  bool
+	newPosts int
+	err      error
+}
+
+// scrapeFeed fetches a claimed feed and stores its new posts. A failed
+// fetch is recorded against the feed before it is returned.
+func scrapeFeed(s *state, sqlFeed database.Feed) scrapeResult {
+	// fetch the feed, skipping it if unchanged since the last fetch
+	result, err := rss.FetchFeedConditional(context.Background(), s.Fetcher, sqlFeed.Url, rss.CacheValidators{
+		ETag:         sqlFeed.Etag,
+		LastModified: sqlFeed.LastModified,
+	})
+	var backoffErr *rss.HostBackoffError
+	if errors.As(err, &backoffErr) {
+		// the host asked for a pause; this is not the feed's fault
+		return scrapeResult{err: deferFeed(s, sqlFeed, time.Until(backoffErr.Until))}
+	}
 	if err != nil {
-		return err
+		return scrapeResult{err: recordFetchFailure(s, sqlFeed, err)}
+	}
+	if sqlFeed.ConsecutiveFailures > 0 || sqlFeed.Status != feedStatusActive {
+		if err := s.DBQueries.RecordFeedSuccess(context.Background(), sqlFeed.ID); err != nil {
+			return scrapeResult{err: fmt.Errorf("error: failed to reset status of %s - %v", sqlFeed.Url, err)}
+		}
+	}
+	if result.MovedTo != "" {
+		sqlFeed, err = moveFeed(s, sqlFeed, result.MovedTo)
+		if err != nil {
+			return scrapeResult{err: err}
+		}
 	}
+	if result.NotModified {
+		return scrapeResult{fetched: true}
+	}
+	rssFeed := result.Feed
 
-	// fetch the feed
-	rssFeed, err := rss.FetchFeed(context.Background(), sqlFeed.Url)
+	err = s.DBQueries.UpdateFeedCacheValidators(context.Background(), database.UpdateFeedCacheValidatorsParams{
+		ID:           sqlFeed.ID,
+		Etag:         result.Validators.ETag,
+		LastModified: result.Validators.LastModified,
+	})
 	if err != nil {
-		return err
+		return scrapeResult{err: fmt.Errorf("error: failed to store cache validators for %s - %v", sqlFeed.Url, err)}
+	}
+
+	if err := updateFeedMetadata(s, sqlFeed, rssFeed); err != nil {
+		return scrapeResult{err: err}
+	}
+
+	if err := updateFeedRefreshHints(s, sqlFeed, rssFeed.Refresh); err != nil {
+		return scrapeResult{err: err}
+	}
+
+	if err := updateFeedWebsub(s, sqlFeed, rssFeed); err != nil {
+		return scrapeResult{err: err}
+	}
+
+	newPosts := storeFeedItems(s, sqlFeed, rssFeed)
+
+	// the legacy posts still in the feed now have their real guids
+	if sqlFeed.LegacyGuids {
+		err := s.DBQueries.UpdateFeedLegacyGuids(context.Background(), database.UpdateFeedLegacyGuidsParams{
+			ID:          sqlFeed.ID,
+			LegacyGuids: false,
+		})
+		if err != nil {
+			return scrapeResult{err: fmt.Errorf("error: failed to update guid state of %s - %v", sqlFeed.Url, err)}
+		}
+	}
+
+	return scrapeResult{fetched: true, newPosts: newPosts}
+}
+
+// newPostParams builds the row stored for a feed item. Authors and
+// Categories are never nil, as pq.Array sends a nil slice as NULL and both
+// columns are NOT NULL.
+func newPostParams(feedID uuid.UUID, item rss.RSSItem, guid string, publishedAt sql.NullTime) database.CreatePostParams {
+	params := database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: item.Description,
+		PublishedAt: publishedAt,
+		FeedID:      feedID,
+		Guid:        guid,
+		Content:     item.Content,
+		Authors:     []string{},
+		Categories:  []string{},
 	}
+	params.Authors = append(params.Authors, item.Authors...)
+	params.Categories = append(params.Categories, item.Categories...)
+	return params
+}
 
-	// iterate and print
+// storeFeedItems writes a feed's items as posts, skipping those already
+// stored, and returns how many new posts were created.
+func storeFeedItems(s *state, sqlFeed database.Feed, rssFeed *rss.RSSFeed) int {
+	created := 0
 	for _, item := range rssFeed.Channel.Item {
-		published_at := parsePublishedTime(item.PubDate)
-		_, err := s.DBQueries.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: item.Description,
-			PublishedAt: published_at,
-			FeedID:      sqlFeed.ID,
+		published_at := parsePublishedTime(item.PubDate, time.Now().UTC())
+
+		// posts stored before guids were tracked had their url copied into
+		// guid; give such a post its real guid so it is not stored again
+		guid := item.Identity()
+		if sqlFeed.LegacyGuids && item.Link != "" && guid != item.Link {
+			err := s.DBQueries.AdoptLegacyPostGuid(context.Background(), database.AdoptLegacyPostGuidParams{
+				Guid:   guid,
+				FeedID: sqlFeed.ID,
+				Url:    item.Link,
+			})
+			if err != nil {
+				fmt.Printf("error: failed to update guid of %s - %v\n", item.Link, err)
+			}
+		}
+
+		sqlPost, err := s.DBQueries.CreatePost(context.Background(), newPostParams(sqlFeed.ID, item, guid, published_at))
+		// no row comes back when the item is already stored for this feed
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				fmt.Println(err.Error())
+			}
+			continue
+		}
+		created++
+
+		if sqlFeed.FetchFullText && item.Link != "" {
+			storeArticle(s, sqlPost)
+		}
+
+		for _, enclosure := range item.Enclosures {
+			_, err := s.DBQueries.CreateEnclosure(context.Background(), database.CreateEnclosureParams{
+				ID:              uuid.New(),
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+				PostID:          sqlPost.ID,
+				Url:             enclosure.URL,
+				MimeType:        enclosure.Type,
+				Length:          enclosure.Length,
+				DurationSeconds: int32(enclosure.Duration / time.Second),
+				ThumbnailUrl:    enclosure.Thumbnail,
+			})
+			if err != nil {
+				fmt.Printf("error: failed to store attachment %s - %v\n", enclosure.URL, err)
+			}
+		}
+	}
+	return created
+}
+
+const (
+	feedStatusActive   = "active"
+	feedStatusDisabled = "disabled"
+
+	defaultDisableAfterFailures = 10
+
+	defaultAggWorkers   = 4
+	defaultAggBatchSize = 20
+
+	temporaryBackoffBase = time.Minute
+	temporaryBackoffMax  = 6 * time.Hour
+	permanentBackoffBase = time.Hour
+	permanentBackoffMax  = 24 * time.Hour
+)
+
+// storeArticle downloads the page a post links to and keeps its main
+// content, for feeds that only carry teasers.
+func storeArticle(s *state, sqlPost database.Post) {
+	article, err := fetchArticle(s, sqlPost.Url)
+	if err != nil {
+		fmt.Printf("error: failed to fetch full text of %s - %v\n", sqlPost.Url, err)
+		return
+	}
+
+	err = s.DBQueries.UpdatePostArticle(context.Background(), database.UpdatePostArticleParams{
+		ID:      sqlPost.ID,
+		Article: article,
+	})
+	if err != nil {
+		fmt.Printf("error: failed to store full text of %s - %v\n", sqlPost.Url, err)
+	}
+}
+
+func fetchArticle(s *state, pageURL string) (string, error) {
+	response, err := s.Fetcher.Get(context.Background(), pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	contentType := response.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "html") {
+		return "", fmt.Errorf("error: %s is not an HTML page (%s)", pageURL, contentType)
+	}
+	return extract.Article(response.Body, contentType, response.FinalURL)
+}
+
+// recordFetchFailure counts a failed fetch against the feed and schedules
+// its next attempt with exponential backoff: within minutes for temporary
+// errors, hours for permanent ones. The feed is disabled once the configured
+// threshold is reached or straight away on 410 Gone. The returned error
+// describes the failure and what happens next, for agg to log.
+func recordFetchFailure(s *state, sqlFeed database.Feed, fetchErr error) error {
+	var statusErr *rss.StatusError
+	gone := errors.As(fetchErr, &statusErr) && statusErr.StatusCode == http.StatusGone
+	temporary := rss.IsTemporary(fetchErr)
+
+	threshold := s.Config.DisableAfterFailures
+	if threshold <= 0 {
+		threshold = defaultDisableAfterFailures
+	}
+
+	retryIn := fetchBackoff(int(sqlFeed.ConsecutiveFailures)+1, temporary)
+	if statusErr != nil && statusErr.RetryAfter > retryIn {
+		retryIn = statusErr.RetryAfter
+	}
+
+	updated, err := s.DBQueries.RecordFeedFailure(context.Background(), database.RecordFeedFailureParams{
+		LastError:      fetchErr.Error(),
+		Disable:        gone,
+		Threshold:      int32(threshold),
+		RetryInSeconds: int32(retryIn.Seconds()),
+		ID:             sqlFeed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to record failure of %s - %v", sqlFeed.Url, err)
+	}
+
+	kind := "permanent"
+	if temporary {
+		kind = "temporary"
+	}
+	if updated.Status == feedStatusDisabled {
+		return fmt.Errorf("error: failed to fetch \"%s\" (%d in a row, %s), feed disabled - %v\nRe-enable it with: gator enable %s",
+			updated.Name, updated.ConsecutiveFailures, kind, fetchErr, updated.Url)
+	}
+	return fmt.Errorf("error: failed to fetch \"%s\" (%d in a row, %s), retrying in %v - %v",
+		updated.Name, updated.ConsecutiveFailures, kind, retryIn.Round(time.Second), fetchErr)
+}
+
+// fetchBackoff is how long to leave a feed alone after its nth consecutive
+// failure: the base delay doubled for each earlier failure, capped, then
+// moved by up to a quarter either way so that feeds which failed together
+// do not all come due together.
+func fetchBackoff(failures int, temporary bool) time.Duration {
+	backoff, limit := permanentBackoffBase, permanentBackoffMax
+	if temporary {
+		backoff, limit = temporaryBackoffBase, temporaryBackoffMax
+	}
+	for i := 1; i < failures && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if backoff > limit {
+		backoff = limit
+	}
+	jitter := time.Duration(rand.Int64N(int64(backoff)/2)) - backoff/4
+	return backoff + jitter
+}
+
+// deferFeed puts off a feed's next fetch without counting it as a failure.
+func deferFeed(s *state, sqlFeed database.Feed, wait time.Duration) error {
+	err := s.DBQueries.DeferFeed(context.Background(), database.DeferFeedParams{
+		RetryInSeconds: int32(wait.Seconds()),
+		ID:             sqlFeed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to defer %s - %v", sqlFeed.Url, err)
+	}
+	return nil
+}
+
+// moveFeed points a permanently redirected feed at its new URL. If another
+// feed already uses that URL, the follows and posts of the old feed are
+// merged into it and the old feed is removed. The surviving feed is returned.
+func moveFeed(s *state, sqlFeed database.Feed, newURL string) (database.Feed, error) {
+	ctx := context.Background()
+
+	target, err := s.DBQueries.GetFeed(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.DBQueries.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:  sqlFeed.ID,
+			Url: newURL,
 		})
 		if err != nil {
-			if !strings.Contains(err.Error(), "posts_url_key") {
+			return sqlFeed, fmt.Errorf("error: failed to update url of %s to %s - %v", sqlFeed.Url, newURL, err)
+		}
+		fmt.Printf("Feed \"%s\" moved permanently from %s to %s\n", sqlFeed.Name, sqlFeed.Url, newURL)
+		sqlFeed.Url = newURL
+		return sqlFeed, nil
+	}
+	if err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to look up feed %s - %v", newURL, err)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to begin transaction - %v", err)
+	}
+	defer tx.Rollback()
+	queries := s.DBQueries.WithTx(tx)
+
+	err = queries.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		ToFeedID:   uuid.NullUUID{UUID: target.ID, Valid: true},
+		FromFeedID: uuid.NullUUID{UUID: sqlFeed.ID, Valid: true},
+	})
+	if err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to move follows of %s - %v", sqlFeed.Url, err)
+	}
+
+	err = queries.MovePosts(ctx, database.MovePostsParams{
+		ToFeedID:   target.ID,
+		FromFeedID: sqlFeed.ID,
+	})
+	if err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to move posts of %s - %v", sqlFeed.Url, err)
+	}
+
+	// posts moved in from a feed with legacy guids still need adopting
+	if sqlFeed.LegacyGuids && !target.LegacyGuids {
+		err = queries.UpdateFeedLegacyGuids(ctx, database.UpdateFeedLegacyGuidsParams{
+			ID:          target.ID,
+			LegacyGuids: true,
+		})
+		if err != nil {
+			return sqlFeed, fmt.Errorf("error: failed to update guid state of %s - %v", target.Url, err)
+		}
+		target.LegacyGuids = true
+	}
+
+	if err := queries.DeleteFeed(ctx, sqlFeed.ID); err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to remove feed %s - %v", sqlFeed.Url, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sqlFeed, fmt.Errorf("error: failed to commit merge of %s - %v", sqlFeed.Url, err)
+	}
+
+	fmt.Printf("Feed \"%s\" moved permanently from %s to %s and was merged into \"%s\"\n", sqlFeed.Name, sqlFeed.Url, newURL, target.Name)
+	return target, nil
+}
+
+// updateFeedMetadata stores the title, site link, description, image and
+// language the feed declares about itself, when they have changed.
+func updateFeedMetadata(s *state, sqlFeed database.Feed, rssFeed *rss.RSSFeed) error {
+	params := database.UpdateFeedMetadataParams{
+		ID:          sqlFeed.ID,
+		Title:       strings.TrimSpace(rssFeed.Channel.Title),
+		SiteUrl:     strings.TrimSpace(rssFeed.Channel.Link),
+		Description: strings.TrimSpace(rssFeed.Channel.Description),
+		ImageUrl:    rssFeed.Image,
+		Language:    strings.TrimSpace(rssFeed.Channel.Language),
+	}
+	if params.Title == sqlFeed.Title && params.SiteUrl == sqlFeed.SiteUrl && params.Description == sqlFeed.Description &&
+		params.ImageUrl == sqlFeed.ImageUrl && params.Language == sqlFeed.Language {
+		return nil
+	}
+
+	if err := s.DBQueries.UpdateFeedMetadata(context.Background(), params); err != nil {
+		return fmt.Errorf("error: failed to store channel details of %s - %v", sqlFeed.Url, err)
+	}
+	return nil
+}
+
+// updateFeedRefreshHints stores the polling interval and skipped hours and
+// days the feed asks for, which ClaimFeedsToFetch honors.
+func updateFeedRefreshHints(s *state, sqlFeed database.Feed, hints rss.RefreshHints) error {
+	params := database.UpdateFeedRefreshHintsParams{
+		ID:                     sqlFeed.ID,
+		RefreshIntervalMinutes: int32(hints.Interval / time.Minute),
+		SkipHours:              []int32{},
+		SkipDays:               []int32{},
+	}
+	for _, hour := range hints.SkipHours {
+		params.SkipHours = append(params.SkipHours, int32(hour))
+	}
+	for _, day := range hints.SkipDays {
+		params.SkipDays = append(params.SkipDays, int32(day))
+	}
+	if params.RefreshIntervalMinutes == sqlFeed.RefreshIntervalMinutes &&
+		slices.Equal(params.SkipHours, sqlFeed.SkipHours) && slices.Equal(params.SkipDays, sqlFeed.SkipDays) {
+		return nil
+	}
+
+	if err := s.DBQueries.UpdateFeedRefreshHints(context.Background(), params); err != nil {
+		return fmt.Errorf("error: failed to store refresh hints of %s - %v", sqlFeed.Url, err)
+	}
+	return nil
+}
+
+// updateFeedWebsub records the hub a feed advertises and the topic URL to
+// subscribe to, so agg can switch the feed over to push delivery.
+func updateFeedWebsub(s *state, sqlFeed database.Feed, rssFeed *rss.RSSFeed) error {
+	topic := rssFeed.Self
+	if topic == "" {
+		topic = sqlFeed.Url
+	}
+	if rssFeed.Hub == "" {
+		topic = ""
+	}
+	if rssFeed.Hub == sqlFeed.WebsubHub && topic == sqlFeed.WebsubTopic {
+		return nil
+	}
+
+	err := s.DBQueries.UpdateFeedWebsub(context.Background(), database.UpdateFeedWebsubParams{
+		ID:          sqlFeed.ID,
+		WebsubHub:   rssFeed.Hub,
+		WebsubTopic: topic,
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to store websub hub of %s - %v", sqlFeed.Url, err)
+	}
+	return nil
+}
+
+const (
+	defaultWebsubListen = ":8080"
+	websubLease         = 10 * 24 * time.Hour
+	websubSyncInterval  = time.Minute
+	websubPollFallback  = 24 * time.Hour
+	// pushes waiting to be ingested before new ones are refused
+	websubDeliveryQueue = 64
+)
+
+// websubStore connects the websub subscriber to the database. Subscriptions
+// are identified by the ID of the feed they belong to.
+type websubStore struct {
+	s          *state
+	deliveries chan websubDelivery
+}
+
+// websubDelivery is content a hub pushed, waiting to be ingested.
+type websubDelivery struct {
+	feedID      uuid.UUID
+	contentType string
+	body        []byte
+}
+
+func (w websubStore) Lookup(ctx context.Context, id string) (websub.Subscription, error) {
+	feedID, err := uuid.Parse(id)
+	if err != nil {
+		return websub.Subscription{}, websub.ErrUnknownSubscription
+	}
+	sub, err := w.s.DBQueries.GetWebsubSubscription(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return websub.Subscription{}, websub.ErrUnknownSubscription
+	}
+	if err != nil {
+		return websub.Subscription{}, fmt.Errorf("error: failed to get websub subscription %s - %v", id, err)
+	}
+	return websub.Subscription{
+		ID:     id,
+		Topic:  sub.TopicUrl,
+		Hub:    sub.HubUrl,
+		Secret: sub.Secret,
+	}, nil
+}
+
+func (w websubStore) Verified(ctx context.Context, id string, lease time.Duration) error {
+	feedID, err := uuid.Parse(id)
+	if err != nil {
+		return websub.ErrUnknownSubscription
+	}
+	if lease <= 0 {
+		lease = websubLease
+	}
+	err = w.s.DBQueries.ActivateWebsubSubscription(ctx, database.ActivateWebsubSubscriptionParams{
+		FeedID:         feedID,
+		LeaseExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(lease), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to activate websub subscription %s - %v", id, err)
+	}
+	fmt.Printf("WebSub subscription for feed %s verified for %v\n", id, lease)
+	return nil
+}
+
+func (w websubStore) Denied(ctx context.Context, id string, reason string) error {
+	feedID, err := uuid.Parse(id)
+	if err != nil {
+		return websub.ErrUnknownSubscription
+	}
+	if err := w.s.DBQueries.DenyWebsubSubscription(ctx, feedID); err != nil {
+		return fmt.Errorf("error: failed to deny websub subscription %s - %v", id, err)
+	}
+	fmt.Printf("WebSub subscription for feed %s denied by hub: %s\n", id, reason)
+	return nil
+}
+
+// Deliver queues pushed content for ingestDeliveries, so the hub is answered
+// without waiting for posts and their articles to be stored.
+func (w websubStore) Deliver(ctx context.Context, id string, contentType string, body []byte) error {
+	feedID, err := uuid.Parse(id)
+	if err != nil {
+		return websub.ErrUnknownSubscription
+	}
+	select {
+	case w.deliveries <- websubDelivery{feedID: feedID, contentType: contentType, body: body}:
+		return nil
+	default:
+		// the hub retries deliveries that fail
+		return fmt.Errorf("error: too many websub deliveries waiting, refused content for %s", id)
+	}
+}
+
+// ingestDeliveries stores the posts of queued deliveries one at a time.
+func (w websubStore) ingestDeliveries() {
+	for delivery := range w.deliveries {
+		if err := w.ingest(delivery); err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+}
+
+func (w websubStore) ingest(delivery websubDelivery) error {
+	sqlFeed, err := w.s.DBQueries.GetFeedByID(context.Background(), delivery.feedID)
+	if err != nil {
+		return fmt.Errorf("error: failed to get feed %s - %v", delivery.feedID, err)
+	}
+	rssFeed, err := rss.ParseFeed(delivery.body, delivery.contentType)
+	if err != nil {
+		return fmt.Errorf("error: failed to parse content pushed for %s - %v", sqlFeed.Url, err)
+	}
+	if err := updateFeedMetadata(w.s, sqlFeed, rssFeed); err != nil {
+		return err
+	}
+	created := storeFeedItems(w.s, sqlFeed, rssFeed)
+	fmt.Printf("WebSub delivery for \"%s\": %d new posts\n", sqlFeed.Name, created)
+	return nil
+}
+
+// startWebsub serves the websub callback and keeps hub subscriptions for
+// followed feeds current. It does nothing unless a callback URL is configured.
+func startWebsub(s *state) error {
+	if s.Config.WebsubCallbackURL == "" {
+		return nil
+	}
+	// hub requests go out like feed fetches, through the same proxy and
+	// with the same TLS, timeout and User-Agent settings
+	options, err := fetchOptions(s.Config)
+	if err != nil {
+		return err
+	}
+	client, err := rss.NewHTTPClient(options)
+	if err != nil {
+		return err
+	}
+	store := websubStore{s: s, deliveries: make(chan websubDelivery, websubDeliveryQueue)}
+	sub, err := websub.NewSubscriber(s.Config.WebsubCallbackURL, client, store)
+	if err != nil {
+		return err
+	}
+	go store.ingestDeliveries()
+
+	listen := s.Config.WebsubListen
+	if listen == "" {
+		listen = defaultWebsubListen
+	}
+	mux := http.NewServeMux()
+	mux.Handle(sub.Path(), sub)
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Printf("error: websub callback server stopped - %v\n", err)
+		}
+	}()
+	fmt.Printf("Listening for WebSub callbacks on %s (%s)\n", listen, s.Config.WebsubCallbackURL)
+
+	go func() {
+		ticker := time.NewTicker(websubSyncInterval)
+		for ; ; <-ticker.C {
+			syncWebsubSubscriptions(s, sub)
+		}
+	}()
+	return nil
+}
+
+// syncWebsubSubscriptions subscribes to the hubs of followed feeds that have
+// no subscription yet, changed hubs, leases close to expiry, or requests the
+// hub never verified.
+func syncWebsubSubscriptions(s *state, sub *websub.Subscriber) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	sqlFeeds, err := s.DBQueries.GetFeedsDueForWebsub(ctx, database.GetFeedsDueForWebsubParams{
+		RenewBefore: now.Add(time.Hour),
+		RetryBefore: now.Add(-time.Hour),
+	})
+	if err != nil {
+		fmt.Printf("error: failed to get feeds due for websub - %v\n", err)
+		return
+	}
+
+	for _, sqlFeed := range sqlFeeds {
+		secret := ""
+		existing, err := s.DBQueries.GetWebsubSubscription(ctx, sqlFeed.ID)
+		if err == nil && existing.HubUrl == sqlFeed.WebsubHub && existing.TopicUrl == sqlFeed.WebsubTopic {
+			secret = existing.Secret
+		}
+		if secret == "" {
+			secret, err = websub.NewSecret()
+			if err != nil {
 				fmt.Println(err.Error())
+				continue
 			}
 		}
+
+		// store the request first so the hub's verification finds it
+		_, err = s.DBQueries.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{
+			FeedID:      sqlFeed.ID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			HubUrl:      sqlFeed.WebsubHub,
+			TopicUrl:    sqlFeed.WebsubTopic,
+			Secret:      secret,
+			RequestedAt: now,
+		})
+		if err != nil {
+			fmt.Printf("error: failed to store websub subscription for %s - %v\n", sqlFeed.Url, err)
+			continue
+		}
+
+		err = sub.Subscribe(ctx, websub.Subscription{
+			ID:     sqlFeed.ID.String(),
+			Topic:  sqlFeed.WebsubTopic,
+			Hub:    sqlFeed.WebsubHub,
+			Secret: secret,
+		}, websubLease)
+		if err != nil {
+			fmt.Printf("error: failed to subscribe to %s at %s - %v\n", sqlFeed.WebsubTopic, sqlFeed.WebsubHub, err)
+			continue
+		}
+		fmt.Printf("Requested WebSub subscription for \"%s\" at %s\n", sqlFeed.Name, sqlFeed.WebsubHub)
 	}
-	return nil
 }
 
 func main() {
@@ -113,8 +860,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	currentState.DB = db
 	currentState.DBQueries = database.New(db)
 
+	currentState.Fetcher, err = newFetcher(currentState.Config)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
 	var commands commands
 	commands.Commands = make(map[string]func(*state, command) error)
 	commands.register("login", handlerLogin)
@@ -128,6 +882,13 @@ func main() {
 	commands.register("following", middlewareLoggedIn(handlerFollowing))
 	commands.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	commands.register("browse", middlewareLoggedIn(handlerBrowse))
+	commands.register("disabled", handlerDisabled)
+	commands.register("enable", handlerEnable)
+	commands.register("fulltext", handlerFullText)
+	commands.register("read", middlewareLoggedIn(handlerRead))
+	commands.register("import-opml", middlewareLoggedIn(handlerImportOPML))
+	commands.register("export-opml", middlewareLoggedIn(handlerExportOPML))
+	commands.register("preview", handlerPreview)
 
 	if len(os.Args) < 2 {
 		fmt.Println("error: not enough arguments")
@@ -241,32 +1002,246 @@ func handlerAgg(s *state, cmd command) error {
 		return fmt.Errorf("error: failed to parse request period - %v", err)
 	}
 
-	fmt.Println("Collecting feeds every ", cmd.Args[0])
+	if err := startWebsub(s); err != nil {
+		return err
+	}
+
+	workers := s.Config.AggWorkers
+	if workers <= 0 {
+		workers = defaultAggWorkers
+	}
+	batchSize := s.Config.AggBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultAggBatchSize
+	}
+
+	fmt.Printf("Collecting feeds every %s with %d workers\n", cmd.Args[0], workers)
 
+	// a cycle that outlasts the period delays the next one rather than
+	// overlapping it, as the ticker drops the ticks it misses
 	ticker := time.NewTicker(time_between_reqs)
 	for ; ; <-ticker.C {
-		err = scrapeFeeds(s)
+		// failures of single feeds are logged and counted by scrapeFeeds;
+		// anything that reaches here, such as a lost database connection,
+		// is logged and the next tick tries again
+		if err := scrapeFeeds(s, workers, batchSize); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// extractFlag reports whether flag appears in args and returns the remaining arguments.
+func extractFlag(args []string, flag string) (bool, []string) {
+	found := false
+	remaining := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		remaining = append(remaining, arg)
+	}
+	return found, remaining
+}
+
+// extractOption returns the value following option in args, or "" when the
+// option is absent, along with the remaining arguments.
+func extractOption(args []string, option string) (string, []string, error) {
+	value := ""
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != option {
+			remaining = append(remaining, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return "", nil, fmt.Errorf("error: no value provided for %s", option)
+		}
+		value = args[i+1]
+		i++
+	}
+	return value, remaining, nil
+}
+
+// resolveFeed follows a page URL to the feed it advertises. When the page
+// lists several feeds the user is asked to pick one unless takeFirst is set.
+// If pageURL is itself a feed, the returned link carries it already parsed.
+func resolveFeed(s *state, pageURL string, takeFirst bool) (rss.FeedLink, error) {
+	candidates, err := rss.DiscoverFeeds(context.Background(), s.Fetcher, pageURL)
+	if err != nil {
+		return rss.FeedLink{}, err
+	}
+
+	if len(candidates) == 0 {
+		return rss.FeedLink{}, fmt.Errorf("error: no feeds found at %s", pageURL)
+	}
+
+	if len(candidates) == 1 || takeFirst {
+		return candidates[0], nil
+	}
+
+	fmt.Printf("Multiple feeds found at %s:\n", pageURL)
+	for i, candidate := range candidates {
+		fmt.Printf("%d. %s [%s] %s\n", i+1, candidate.Title, candidate.Type, candidate.URL)
+	}
+	fmt.Print("Select a feed (default 1): ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return candidates[0], nil
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return candidates[0], nil
+	}
+
+	choice, err := strconv.Atoi(line)
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return rss.FeedLink{}, fmt.Errorf("error: invalid feed selection %q", line)
+	}
+	return candidates[choice-1], nil
+}
+
+// fetchPreview fetches and parses a feed without storing anything, failing
+// for URLs that do not serve a feed.
+func fetchPreview(s *state, feedURL string) (*rss.RSSFeed, error) {
+	result, err := rss.FetchFeedConditional(context.Background(), s.Fetcher, feedURL, rss.CacheValidators{})
+	if err != nil {
+		return nil, fmt.Errorf("error: %s is not a usable feed - %v", feedURL, err)
+	}
+	return result.Feed, nil
+}
+
+const defaultPreviewItems = 5
+
+func handlerPreview(s *state, cmd command) error {
+	takeFirst, args := extractFlag(cmd.Args, "--first")
+	if len(args) == 0 {
+		return errors.New("error: no url provided")
+	}
+
+	count := defaultPreviewItems
+	if len(args) > 1 {
+		var err error
+		count, err = strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return errors.New("error: invalid item count")
+		}
+	}
+
+	link, err := resolveFeed(s, args[0], takeFirst)
+	if err != nil {
+		return err
+	}
+	if link.URL != args[0] {
+		fmt.Printf("Discovered feed at %s\n", link.URL)
+	}
+
+	rssFeed := link.Feed
+	if rssFeed == nil {
+		rssFeed, err = fetchPreview(s, link.URL)
 		if err != nil {
 			return err
 		}
 	}
+
+	fmt.Printf("Title: \"%s\"\n", rssFeed.Channel.Title)
+	if rssFeed.Channel.Link != "" {
+		fmt.Printf("Site: %s\n", rssFeed.Channel.Link)
+	}
+	if rssFeed.Channel.Description != "" {
+		description := render.HTMLToText(rssFeed.Channel.Description, render.Options{MaxLength: defaultSummaryLength})
+		fmt.Printf("Description: %s\n", strings.Join(strings.Fields(description), " "))
+	}
+	fmt.Printf("Items: %d\n", len(rssFeed.Channel.Item))
+
+	items := latestItems(rssFeed.Channel.Item, count)
+	if len(items) > 0 {
+		fmt.Println("Latest items:")
+	}
+	for _, item := range items {
+		fmt.Printf("\n\t* \"%s\"\n", item.Title)
+		if published, ok := pubdate.Parse(item.PubDate); ok {
+			fmt.Printf("\t* Published: %v\n", published)
+		}
+		if item.Link != "" {
+			fmt.Printf("\t* URL: %s\n", item.Link)
+		}
+	}
+	return nil
+}
+
+// latestItems returns up to count items, newest first. Items without a
+// readable date keep their feed order after the dated ones.
+func latestItems(items []rss.RSSItem, count int) []rss.RSSItem {
+	published := make(map[int]time.Time, len(items))
+	order := make([]int, len(items))
+	for i, item := range items {
+		order[i] = i
+		if date, ok := pubdate.Parse(item.PubDate); ok {
+			published[i] = date
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		dateA, okA := published[order[a]]
+		dateB, okB := published[order[b]]
+		if okA && okB {
+			return dateA.After(dateB)
+		}
+		return okA && !okB
+	})
+
+	latest := make([]rss.RSSItem, 0, count)
+	for _, i := range order {
+		if len(latest) == count {
+			break
+		}
+		latest = append(latest, items[i])
+	}
+	return latest
 }
 
 func handlerAddFeed(s *state, cmd command, sqlUser database.User) error {
-	if len(cmd.Args) == 0 {
+	takeFirst, args := extractFlag(cmd.Args, "--first")
+	force, args := extractFlag(args, "--force")
+	if len(args) == 0 {
 		return errors.New("error: no name provided")
 	}
 
-	if len(cmd.Args) == 1 {
+	if len(args) == 1 {
 		return errors.New("error: no url provided")
 	}
 
+	// --force stores the url exactly as given, without fetching it
+	feedURL := args[1]
+	if !force {
+		link, err := resolveFeed(s, args[1], takeFirst)
+		if err != nil {
+			return fmt.Errorf("%v\nUse --force to add it anyway", err)
+		}
+		feedURL = link.URL
+		if feedURL != args[1] {
+			fmt.Printf("Discovered feed at %s\n", feedURL)
+		}
+
+		// a page only names its feeds, which still have to be checked
+		rssFeed := link.Feed
+		if rssFeed == nil {
+			rssFeed, err = fetchPreview(s, feedURL)
+			if err != nil {
+				return fmt.Errorf("%v\nUse --force to add it anyway", err)
+			}
+		}
+		fmt.Printf("Found \"%s\" with %d items\n", rssFeed.Channel.Title, len(rssFeed.Channel.Item))
+	}
+
 	newSqlFeed, err := s.DBQueries.CreateFeed(context.Background(), database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		Name:      cmd.Args[0],
-		Url:       cmd.Args[1],
+		Name:      args[0],
+		Url:       feedURL,
 		UserID:    uuid.NullUUID{UUID: sqlUser.ID, Valid: true},
 	})
 	if err != nil {
@@ -308,6 +1283,32 @@ func handlerFeeds(s *state, cmd command) error {
 		URL: %s
 		Creator: %s
 		`, feed.Name, feed.Url, sqlUser.Name)
+		// the name given to addfeed takes precedence over the feed's own title
+		if feed.Title != "" && feed.Title != feed.Name {
+			fmt.Printf("Title: \"%s\"\n\t\t", feed.Title)
+		}
+		if feed.SiteUrl != "" {
+			fmt.Printf("Site: %s\n\t\t", feed.SiteUrl)
+		}
+		if feed.Description != "" {
+			description := render.HTMLToText(feed.Description, render.Options{MaxLength: defaultSummaryLength})
+			fmt.Printf("Description: %s\n\t\t", strings.Join(strings.Fields(description), " "))
+		}
+		if feed.ImageUrl != "" {
+			fmt.Printf("Image: %s\n\t\t", feed.ImageUrl)
+		}
+		if feed.Language != "" {
+			fmt.Printf("Language: %s\n\t\t", feed.Language)
+		}
+		if refresh := describeRefreshHints(feed); refresh != "" {
+			fmt.Printf("Refresh: %s\n\t\t", refresh)
+		}
+		if feed.Status != feedStatusActive {
+			fmt.Printf("Status: %s (%d failures, last error: %s)\n\t\t", feed.Status, feed.ConsecutiveFailures, feed.LastError)
+		}
+		if feed.Status != feedStatusDisabled && feed.NextAttemptAt.Valid && feed.NextAttemptAt.Time.After(time.Now()) {
+			fmt.Printf("Next attempt: %s\n\t\t", feed.NextAttemptAt.Time.Format(time.DateTime))
+		}
 		fmt.Println()
 	}
 
@@ -317,14 +1318,93 @@ func handlerFeeds(s *state, cmd command) error {
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, sqlUser database.User) error {
+func handlerDisabled(s *state, cmd command) error {
+	sqlFeeds, err := s.DBQueries.GetDisabledFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error: failed to retrieve disabled feeds - %v", err)
+	}
+
+	if len(sqlFeeds) == 0 {
+		fmt.Println("No feeds are disabled.")
+		return nil
+	}
+
+	for _, feed := range sqlFeeds {
+		fmt.Printf("* \"%s\" %s\n", feed.Name, feed.Url)
+		fmt.Printf("  Disabled after %d failures, last error: %s\n", feed.ConsecutiveFailures, feed.LastError)
+	}
+	return nil
+}
+
+func handlerEnable(s *state, cmd command) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("error: no url provided")
+	}
+
+	enabled, err := s.DBQueries.EnableFeed(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("error: failed to enable feed %s - %v", cmd.Args[0], err)
+	}
+	if enabled == 0 {
+		return fmt.Errorf("error: no feeds added using url %s", cmd.Args[0])
+	}
+
+	fmt.Printf("Feed at %s has been re-enabled.\n", cmd.Args[0])
+	return nil
+}
+
+func handlerFullText(s *state, cmd command) error {
 	if len(cmd.Args) == 0 {
 		return errors.New("error: no url provided")
 	}
 
-	sqlFeed, err := s.DBQueries.GetFeed(context.Background(), cmd.Args[0])
+	fetchFullText := true
+	if len(cmd.Args) > 1 {
+		switch cmd.Args[1] {
+		case "on":
+		case "off":
+			fetchFullText = false
+		default:
+			return fmt.Errorf("error: expected on or off, got %s", cmd.Args[1])
+		}
+	}
+
+	updated, err := s.DBQueries.SetFeedFetchFullText(context.Background(), database.SetFeedFetchFullTextParams{
+		Url:           cmd.Args[0],
+		FetchFullText: fetchFullText,
+	})
+	if err != nil {
+		return fmt.Errorf("error: failed to update feed %s - %v", cmd.Args[0], err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("error: no feeds added using url %s", cmd.Args[0])
+	}
+
+	if fetchFullText {
+		fmt.Printf("Full articles will be fetched for new posts of %s.\n", cmd.Args[0])
+	} else {
+		fmt.Printf("Full articles will no longer be fetched for %s.\n", cmd.Args[0])
+	}
+	return nil
+}
+
+func handlerFollow(s *state, cmd command, sqlUser database.User) error {
+	takeFirst, args := extractFlag(cmd.Args, "--first")
+	if len(args) == 0 {
+		return errors.New("error: no url provided")
+	}
+
+	sqlFeed, err := s.DBQueries.GetFeed(context.Background(), args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		// the url may be a page advertising a feed that has already been added
+		link, discoverErr := resolveFeed(s, args[0], takeFirst)
+		if discoverErr != nil {
+			return fmt.Errorf("error: no feeds added using url %s - %v", args[0], discoverErr)
+		}
+		sqlFeed, err = s.DBQueries.GetFeed(context.Background(), link.URL)
+	}
 	if err != nil {
-		return fmt.Errorf("error: no feeds added using url %s - %v", cmd.Args[0], err)
+		return fmt.Errorf("error: no feeds added using url %s - %v", args[0], err)
 	}
 
 	_, err = s.DBQueries.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
@@ -342,6 +1422,155 @@ func handlerFollow(s *state, cmd command, sqlUser database.User) error {
 	return nil
 }
 
+func handlerImportOPML(s *state, cmd command, sqlUser database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("error: no OPML file provided")
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("error: failed to open %s - %v", cmd.Args[0], err)
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	userID := uuid.NullUUID{UUID: sqlUser.ID, Valid: true}
+
+	sqlFeedFollows, err := s.DBQueries.GetFeedFollowsForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error: failed to retreive follow records for user %s - %v", sqlUser.Name, err)
+	}
+	following := map[uuid.UUID]bool{}
+	for _, follow := range sqlFeedFollows {
+		following[follow.FeedID.UUID] = true
+	}
+
+	var added, followed int
+	var duplicates, failures []string
+	seen := map[string]bool{}
+	for _, subscription := range doc.Subscriptions() {
+		if seen[subscription.XMLURL] {
+			duplicates = append(duplicates, fmt.Sprintf("%s (listed more than once)", subscription.XMLURL))
+			continue
+		}
+		seen[subscription.XMLURL] = true
+
+		name := subscription.Title
+		if name == "" {
+			name = subscription.XMLURL
+		}
+
+		sqlFeed, err := s.DBQueries.GetFeed(ctx, subscription.XMLURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			sqlFeed, err = s.DBQueries.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       subscription.XMLURL,
+				UserID:    userID,
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to create feed - %v", subscription.XMLURL, err))
+				continue
+			}
+			added++
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: failed to look up feed - %v", subscription.XMLURL, err))
+			continue
+		}
+
+		feedID := uuid.NullUUID{UUID: sqlFeed.ID, Valid: true}
+		if following[sqlFeed.ID] {
+			duplicates = append(duplicates, fmt.Sprintf("%s (already followed)", subscription.XMLURL))
+		} else {
+			_, err = s.DBQueries.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    userID,
+				FeedID:    feedID,
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to follow feed - %v", subscription.XMLURL, err))
+				continue
+			}
+			following[sqlFeed.ID] = true
+			followed++
+		}
+
+		if len(subscription.Categories) > 0 {
+			err = s.DBQueries.SetFeedFollowCategories(ctx, database.SetFeedFollowCategoriesParams{
+				UserID:     userID,
+				FeedID:     feedID,
+				Categories: subscription.Categories,
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to store categories - %v", subscription.XMLURL, err))
+			}
+		}
+	}
+
+	fmt.Printf("Imported %s: %d feeds added, %d newly followed, %d duplicates, %d failures\n", cmd.Args[0], added, followed, len(duplicates), len(failures))
+	if len(duplicates) > 0 {
+		fmt.Println("Duplicates:")
+		for _, duplicate := range duplicates {
+			fmt.Printf("* %s\n", duplicate)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Println("Failures:")
+		for _, failure := range failures {
+			fmt.Printf("* %s\n", failure)
+		}
+	}
+	return nil
+}
+
+// handlerExportOPML writes the current user's follows as OPML to the given
+// file, or to stdout when no file is given.
+func handlerExportOPML(s *state, cmd command, sqlUser database.User) error {
+	sqlFeedFollows, err := s.DBQueries.GetFeedFollowsForUser(context.Background(), uuid.NullUUID{UUID: sqlUser.ID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("error: failed to retreive follow records for user %s - %v", sqlUser.Name, err)
+	}
+
+	var subscriptions []opml.Subscription
+	for _, follow := range sqlFeedFollows {
+		subscriptions = append(subscriptions, opml.Subscription{
+			Title:      follow.FeedName,
+			XMLURL:     follow.FeedUrl,
+			HTMLURL:    follow.FeedSiteUrl,
+			Categories: follow.Categories,
+		})
+	}
+	doc := opml.New(fmt.Sprintf("gator subscriptions of %s", sqlUser.Name), subscriptions)
+
+	if len(cmd.Args) == 0 {
+		return doc.Write(os.Stdout)
+	}
+
+	file, err := os.Create(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("error: failed to create %s - %v", cmd.Args[0], err)
+	}
+	if err := doc.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error: failed to write %s - %v", cmd.Args[0], err)
+	}
+
+	fmt.Printf("Exported %d feeds followed by %s to %s\n", len(subscriptions), sqlUser.Name, cmd.Args[0])
+	return nil
+}
+
 func handlerFollowing(s *state, cmd command, sqlUser database.User) error {
 	sqlFeedFollows, err := s.DBQueries.GetFeedFollowsForUser(context.Background(), uuid.NullUUID{UUID: sqlUser.ID, Valid: true})
 	if err != nil {
@@ -355,7 +1584,11 @@ func handlerFollowing(s *state, cmd command, sqlUser database.User) error {
 
 	fmt.Printf("%s is currently following:\n", s.Config.CurrentUserName)
 	for _, follows := range sqlFeedFollows {
-		fmt.Printf("* \"%s\"\n", follows.FeedName)
+		if len(follows.Categories) > 0 {
+			fmt.Printf("* \"%s\" [%s]\n", follows.FeedName, strings.Join(follows.Categories, ", "))
+		} else {
+			fmt.Printf("* \"%s\"\n", follows.FeedName)
+		}
 	}
 
 	return nil
@@ -379,25 +1612,188 @@ func handlerUnfollow(s *state, cmd command, sqlUser database.User) error {
 }
 
 func handlerBrowse(s *state, cmd command, sqlUser database.User) error {
+	author, args, err := extractOption(cmd.Args, "--author")
+	if err != nil {
+		return err
+	}
+	category, args, err := extractOption(args, "--category")
+	if err != nil {
+		return err
+	}
+
 	limit := 2
-	if len(cmd.Args) > 0 {
-		var err error
-		limit, err = strconv.Atoi(cmd.Args[0])
+	if len(args) > 0 {
+		limit, err = strconv.Atoi(args[0])
 		if err != nil {
 			return errors.New("error: invalid post limit")
 		}
 	}
 
 	sqlPosts, err := s.DBQueries.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: uuid.NullUUID{UUID: sqlUser.ID, Valid: true},
-		Limit:  int32(limit),
+		UserID:   uuid.NullUUID{UUID: sqlUser.ID, Valid: true},
+		Author:   author,
+		Category: category,
+		Limit:    int32(limit),
 	})
 	if err != nil {
 		return fmt.Errorf("error: failed to retrieve posts for %s - %v", s.Config.CurrentUserName, err)
 	}
 
 	for _, post := range sqlPosts {
-		fmt.Printf("\n\t* \"%s\"\n\t* \"%s\"\n\t* Published: %v\n\t* URL: %s\n", post.Title, post.Description, post.PublishedAt.Time, post.Url)
+		fmt.Printf("\n\t* \"%s\"\n", post.Title)
+		text := post.Description
+		if post.Article != "" {
+			text = post.Article
+		}
+		summary := render.HTMLToText(text, summaryOptions(s.Config, post.Url))
+		if summary != "" {
+			fmt.Println(indentLines(summary, "\t  "))
+		}
+		fmt.Printf("\t* Published: %v\n\t* URL: %s\n", post.PublishedAt.Time, post.Url)
+		if post.Article != "" {
+			fmt.Printf("\t* Full article: gator read %s\n", post.Url)
+		}
+		if len(post.Authors) > 0 {
+			fmt.Printf("\t* Author: %s\n", strings.Join(post.Authors, ", "))
+		}
+		if len(post.Categories) > 0 {
+			fmt.Printf("\t* Categories: %s\n", strings.Join(post.Categories, ", "))
+		}
+
+		sqlEnclosures, err := s.DBQueries.GetEnclosuresForPost(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("error: failed to retrieve attachments for \"%s\" - %v", post.Title, err)
+		}
+		for _, enclosure := range sqlEnclosures {
+			fmt.Printf("\t* Attachment: %s\n", describeEnclosure(enclosure))
+		}
+	}
+	return nil
+}
+
+func handlerRead(s *state, cmd command, sqlUser database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("error: no post url provided")
+	}
+
+	post, err := s.DBQueries.GetPostForUser(context.Background(), database.GetPostForUserParams{
+		Url:    cmd.Args[0],
+		UserID: uuid.NullUUID{UUID: sqlUser.ID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error: no post with url %s in feeds followed by %s", cmd.Args[0], sqlUser.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("error: failed to retrieve post %s - %v", cmd.Args[0], err)
+	}
+
+	// prefer the extracted article, then the feed's full content
+	text := post.Article
+	if text == "" {
+		text = post.Content
+	}
+	if text == "" {
+		text = post.Description
 	}
+
+	fmt.Printf("%s\n\n", post.Title)
+	fmt.Printf("Published: %v\nURL: %s\n", post.PublishedAt.Time, post.Url)
+	if len(post.Authors) > 0 {
+		fmt.Printf("Author: %s\n", strings.Join(post.Authors, ", "))
+	}
+	fmt.Printf("\n%s\n", render.HTMLToText(text, render.Options{
+		Width:   terminalWidth(s.Config),
+		BaseURL: post.Url,
+	}))
 	return nil
 }
+
+const (
+	defaultSummaryLength = 400
+	defaultTerminalWidth = 80
+	// browse indents post text by a tab and two spaces
+	browseIndentWidth = 10
+)
+
+// summaryOptions lays out post descriptions for browse using the
+// summary_length and terminal_width config settings, falling back to
+// $COLUMNS for the width.
+func summaryOptions(cfg *config.Config, postURL string) render.Options {
+	length := cfg.SummaryLength
+	if length == 0 {
+		length = defaultSummaryLength
+	}
+
+	return render.Options{
+		Width:     terminalWidth(cfg) - browseIndentWidth,
+		MaxLength: length,
+		BaseURL:   postURL,
+	}
+}
+
+// terminalWidth is the terminal_width config setting, or $COLUMNS, or 80.
+func terminalWidth(cfg *config.Config) int {
+	width := cfg.TerminalWidth
+	if width <= 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if width <= 0 {
+		width = defaultTerminalWidth
+	}
+	return width
+}
+
+func indentLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeRefreshHints summarizes the polling hints a feed declared.
+func describeRefreshHints(feed database.Feed) string {
+	var details []string
+	if feed.RefreshIntervalMinutes > 0 {
+		details = append(details, fmt.Sprintf("at most every %v", time.Duration(feed.RefreshIntervalMinutes)*time.Minute))
+	}
+	if len(feed.SkipHours) > 0 {
+		var hours []string
+		for _, hour := range feed.SkipHours {
+			hours = append(hours, fmt.Sprintf("%02d:00", hour))
+		}
+		details = append(details, "skipping "+strings.Join(hours, ", ")+" UTC")
+	}
+	if len(feed.SkipDays) > 0 {
+		var days []string
+		for _, day := range feed.SkipDays {
+			days = append(days, time.Weekday(day).String())
+		}
+		details = append(details, "not on "+strings.Join(days, ", "))
+	}
+	return strings.Join(details, "; ")
+}
+
+// describeEnclosure formats an attachment's URL followed by whatever metadata the feed supplied.
+func describeEnclosure(enclosure database.Enclosure) string {
+	var details []string
+	if enclosure.MimeType != "" {
+		details = append(details, enclosure.MimeType)
+	}
+	if enclosure.Length > 0 {
+		details = append(details, fmt.Sprintf("%.1f MB", float64(enclosure.Length)/(1<<20)))
+	}
+	if enclosure.DurationSeconds > 0 {
+		details = append(details, (time.Duration(enclosure.DurationSeconds) * time.Second).String())
+	}
+	if enclosure.ThumbnailUrl != "" {
+		details = append(details, "thumbnail "+enclosure.ThumbnailUrl)
+	}
+
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/notsoexpert/goblogaggregator/internal/rss"
+)
+
+func TestNewPostParamsArrays(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+	}{
+		{"none", nil},
+		{"some", []string{"go", "rss"}},
+	}
+	for _, tt := range tests {
+		item := rss.RSSItem{Title: "Post", Link: "https://example.com/post", Authors: tt.values, Categories: tt.values}
+		params := newPostParams(uuid.New(), item, item.Identity(), sql.NullTime{})
+
+		// posts.authors and posts.categories are NOT NULL, so the values
+		// sent must never be NULL
+		for column, values := range map[string][]string{"authors": params.Authors, "categories": params.Categories} {
+			value, err := pq.Array(values).Value()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if value == nil {
+				t.Errorf("%s: %s sent as NULL, want an array", tt.name, column)
+			}
+			if len(values) != len(tt.values) {
+				t.Errorf("%s: %s = %q, want %q", tt.name, column, values, tt.values)
+			}
+		}
+	}
+}
@@ -21,6 +21,8 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 -- name: GetFeedFollowsForUser :many
 SELECT feed_follows.*,
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
     users.name AS user_name
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
@@ -29,4 +31,18 @@ WHERE feed_follows.user_id = $1;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
-WHERE user_id IN (SELECT id FROM users WHERE users.name = $1) AND feed_id IN (SELECT id FROM feeds WHERE feeds.url = $2);
\ No newline at end of file
+WHERE user_id IN (SELECT id FROM users WHERE users.name = $1) AND feed_id IN (SELECT id FROM feeds WHERE feeds.url = $2);
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg('to_feed_id'), updated_at = NOW()
+WHERE feed_follows.feed_id = sqlc.arg('from_feed_id')
+    AND NOT EXISTS (
+        SELECT 1 FROM feed_follows AS existing
+        WHERE existing.feed_id = sqlc.arg('to_feed_id') AND existing.user_id = feed_follows.user_id
+    );
+
+-- name: SetFeedFollowCategories :exec
+UPDATE feed_follows
+SET categories = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
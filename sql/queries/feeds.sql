@@ -17,13 +17,121 @@ SELECT * FROM feeds;
 SELECT * FROM feeds
 WHERE url = $1;
 
--- name: MarkFeedFetched :exec
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetDatabaseTime :one
+SELECT NOW()::TIMESTAMPTZ AS now;
+
+-- name: ClaimFeedsToFetch :many
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE status <> 'disabled'
+        AND (last_fetched_at IS NULL OR (
+            last_fetched_at + MAKE_INTERVAL(mins => refresh_interval_minutes) <= NOW()
+            AND last_fetched_at < sqlc.arg('cycle_start')::TIMESTAMPTZ
+        ))
+        AND NOT (EXTRACT(HOUR FROM NOW() AT TIME ZONE 'UTC')::INTEGER = ANY(skip_hours))
+        AND NOT (EXTRACT(DOW FROM NOW() AT TIME ZONE 'UTC')::INTEGER = ANY(skip_days))
+        AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+        AND NOT EXISTS (
+            SELECT 1 FROM websub_subscriptions
+            WHERE websub_subscriptions.feed_id = feeds.id
+                AND websub_subscriptions.status = 'active'
+                AND websub_subscriptions.lease_expires_at > sqlc.arg('lease_valid_at')::TIMESTAMP
+                AND feeds.last_fetched_at + MAKE_INTERVAL(mins => sqlc.arg('push_fallback_minutes')::INTEGER) > NOW()
+        )
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET status = 'active', consecutive_failures = 0, last_error = '', next_attempt_at = NULL, updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = sqlc.arg('last_error'),
+    status = CASE
+        WHEN sqlc.arg('disable')::BOOLEAN OR consecutive_failures + 1 >= sqlc.arg('threshold')::INTEGER THEN 'disabled'
+        ELSE 'erroring'
+    END,
+    next_attempt_at = NOW() + MAKE_INTERVAL(secs => sqlc.arg('retry_in_seconds')::INTEGER),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: DeferFeed :exec
+UPDATE feeds
+SET next_attempt_at = NOW() + MAKE_INTERVAL(secs => sqlc.arg('retry_in_seconds')::INTEGER), updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: GetDisabledFeeds :many
 SELECT * FROM feeds
-ORDER BY last_fetched_at NULLS FIRST 
-LIMIT 1;
+WHERE status = 'disabled'
+ORDER BY updated_at DESC;
+
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET status = 'active', consecutive_failures = 0, last_error = '', next_attempt_at = NULL, updated_at = NOW()
+WHERE url = $1;
+
+-- name: UpdateFeedWebsub :exec
+UPDATE feeds
+SET websub_hub = $2, websub_topic = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFeedsDueForWebsub :many
+SELECT feeds.* FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.websub_hub <> ''
+    AND feeds.status <> 'disabled'
+    AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+    AND (
+        websub_subscriptions.feed_id IS NULL
+        OR websub_subscriptions.hub_url <> feeds.websub_hub
+        OR websub_subscriptions.topic_url <> feeds.websub_topic
+        OR (websub_subscriptions.status = 'active' AND websub_subscriptions.lease_expires_at < sqlc.arg('renew_before')::TIMESTAMP)
+        OR (websub_subscriptions.status = 'pending' AND websub_subscriptions.requested_at < sqlc.arg('retry_before')::TIMESTAMP)
+    );
 
+-- name: SetFeedFetchFullText :execrows
+UPDATE feeds
+SET fetch_full_text = $2, updated_at = NOW()
+WHERE url = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, site_url = $3, description = $4, image_url = $5, language = $6, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET refresh_interval_minutes = $2, skip_hours = $3, skip_days = $4, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedLegacyGuids :exec
+UPDATE feeds
+SET legacy_guids = $2, updated_at = NOW()
+WHERE id = $1;
@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, authors, categories)
 VALUES (
     $1,
     $2,
@@ -8,10 +8,26 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
 )
+ON CONFLICT (feed_id, guid) DO NOTHING
 RETURNING *;
 
+-- name: AdoptLegacyPostGuid :exec
+UPDATE posts
+SET guid = sqlc.arg('guid'), updated_at = NOW()
+WHERE posts.feed_id = sqlc.arg('feed_id')
+    AND posts.guid = sqlc.arg('url')
+    AND posts.url = sqlc.arg('url')
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = sqlc.arg('feed_id') AND existing.guid = sqlc.arg('guid')
+    );
+
 -- name: GetAllPosts :many
 SELECT * FROM posts;
 
@@ -24,10 +40,37 @@ SELECT * FROM posts
 WHERE feed_id = $1;
 
 -- name: GetPostsForUser :many
-SELECT title, url, description, published_at FROM posts WHERE feed_id IN 
-    (SELECT feed_id FROM feed_follows WHERE user_id = $1)
+SELECT id, title, url, description, published_at, authors, categories, article FROM posts WHERE feed_id IN 
+    (SELECT feed_id FROM feed_follows WHERE user_id = sqlc.arg('user_id'))
+    AND (sqlc.arg('author')::TEXT = '' OR EXISTS (
+        SELECT 1 FROM unnest(posts.authors) AS author WHERE lower(author) = lower(sqlc.arg('author')::TEXT)
+    ))
+    AND (sqlc.arg('category')::TEXT = '' OR EXISTS (
+        SELECT 1 FROM unnest(posts.categories) AS category WHERE lower(category) = lower(sqlc.arg('category')::TEXT)
+    ))
 ORDER BY published_at DESC NULLS LAST
-LIMIT $2;
+LIMIT sqlc.arg('limit');
 
 -- name: ResetPosts :exec
-DELETE FROM posts;
\ No newline at end of file
+DELETE FROM posts;
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg('to_feed_id'), updated_at = NOW()
+WHERE posts.feed_id = sqlc.arg('from_feed_id')
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = sqlc.arg('to_feed_id') AND existing.guid = posts.guid
+    );
+
+-- name: GetPostForUser :one
+SELECT * FROM posts
+WHERE url = sqlc.arg('url')
+    AND feed_id IN (SELECT feed_id FROM feed_follows WHERE user_id = sqlc.arg('user_id'))
+ORDER BY published_at DESC NULLS LAST
+LIMIT 1;
+
+-- name: UpdatePostArticle :exec
+UPDATE posts
+SET article = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,37 @@
+-- name: UpsertWebsubSubscription :one
+INSERT INTO websub_subscriptions (feed_id, created_at, updated_at, hub_url, topic_url, secret, requested_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    requested_at = EXCLUDED.requested_at,
+    status = CASE
+        WHEN websub_subscriptions.hub_url = EXCLUDED.hub_url AND websub_subscriptions.topic_url = EXCLUDED.topic_url
+            THEN websub_subscriptions.status
+        ELSE 'pending'
+    END
+RETURNING *;
+
+-- name: GetWebsubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: ActivateWebsubSubscription :exec
+UPDATE websub_subscriptions
+SET status = 'active', lease_expires_at = $2, updated_at = NOW()
+WHERE feed_id = $1;
+
+-- name: DenyWebsubSubscription :exec
+UPDATE websub_subscriptions
+SET status = 'denied', lease_expires_at = NULL, updated_at = NOW()
+WHERE feed_id = $1;
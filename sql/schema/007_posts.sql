@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+
+UPDATE posts SET guid = url;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key,
+ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+-- urls were unique across feeds before this migration; keep the oldest post
+-- for each url so the constraint can be restored
+DELETE FROM posts
+USING posts AS kept
+WHERE posts.url = kept.url
+    AND (posts.created_at, posts.id) > (kept.created_at, kept.id);
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_guid_key,
+ADD CONSTRAINT posts_url_key UNIQUE (url),
+DROP COLUMN guid;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN websub_hub TEXT NOT NULL DEFAULT '',
+ADD COLUMN websub_topic TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE websub_subscriptions (
+	feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active', 'denied')),
+    requested_at TIMESTAMP NOT NULL,
+    lease_expires_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
+
+ALTER TABLE feeds
+DROP COLUMN websub_hub,
+DROP COLUMN websub_topic;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN refresh_interval_minutes INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+ADD COLUMN skip_days INTEGER[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN refresh_interval_minutes,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN authors TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE posts SET authors = string_to_array(author, ', ') WHERE author <> '';
+
+ALTER TABLE posts
+DROP COLUMN author;
+
+-- +goose Down
+ALTER TABLE posts
+ADD COLUMN author TEXT NOT NULL DEFAULT '';
+
+UPDATE posts SET author = array_to_string(authors, ', ');
+
+ALTER TABLE posts
+DROP COLUMN authors;
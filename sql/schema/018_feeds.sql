@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN legacy_guids BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- posts stored before guids were tracked had their url copied into guid
+UPDATE feeds SET legacy_guids = TRUE
+WHERE EXISTS (SELECT 1 FROM posts WHERE posts.feed_id = feeds.id AND posts.guid = posts.url);
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN legacy_guids;